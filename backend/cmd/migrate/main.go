@@ -0,0 +1,101 @@
+package main
+
+import (
+	"backend/internal/store"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+func main() {
+	defaultPath := os.Getenv("DB_PATH")
+	if defaultPath == "" {
+		defaultPath = "steam_data.db"
+	}
+	dbPath := flag.String("db", defaultPath, "path to the SQLite database")
+	databaseURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "PostgreSQL connection URL; overrides -db")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-db path | -database-url url] status | up | down [steps] | to version\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	switch flag.Arg(0) {
+	case "status", "up", "down":
+	case "to":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var s store.Database
+	var err error
+	if *databaseURL != "" {
+		s, err = store.OpenPostgresStore(*databaseURL)
+	} else {
+		s, err = store.OpenSQLiteStore(*dbPath)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer s.Close()
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, m := range status {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", m.Version, m.Name, applied)
+		}
+
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed after %d applied: %v", n, err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", n)
+
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps: %q", flag.Arg(1))
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("Rollback failed after %d rolled back: %v", n, err)
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", n)
+
+	case "to":
+		version, err := strconv.Atoi(flag.Arg(1))
+		if err != nil || version < 0 {
+			log.Fatalf("Invalid version: %q", flag.Arg(1))
+		}
+		n, err := migrator.To(ctx, version)
+		if err != nil {
+			log.Fatalf("Migration failed after %d ran: %v", n, err)
+		}
+		fmt.Printf("Ran %d migration(s); now at version %d\n", n, version)
+	}
+}
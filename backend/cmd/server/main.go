@@ -1,12 +1,21 @@
 package main
 
 import (
+	"backend/internal/auth"
 	"backend/internal/handlers"
 	"backend/internal/service"
 	"backend/internal/store"
+	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -22,59 +31,313 @@ func main() {
 		port = "8080"
 	}
 
-	dbPath := "steam_data.db"
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "steam_data.db"
+	}
+
+	// Background workers stop when the process is asked to shut down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var background sync.WaitGroup
+	goBackground := func(run func(context.Context)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run(ctx)
+		}()
+	}
 
-	// Init Store
-	s, err := store.NewSQLiteStore(dbPath)
+	// Init Store: PostgreSQL when DATABASE_URL is set, so several servers
+	// can share it, otherwise a local SQLite file.
+	var s store.Database
+	var err error
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		s, err = store.NewPostgresStore(databaseURL)
+	} else {
+		s, err = store.NewSQLiteStore(dbPath)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize store: %v", err)
 	}
 	defer s.Close()
 
+	// Init Cache
+	cacheSize := envInt("STEAM_CACHE_SIZE", 1000)
+	var persistentCache store.CacheStore
+	if os.Getenv("STEAM_CACHE_PERSIST") == "true" {
+		persistentCache = s
+		if n, err := s.PurgeExpiredCacheEntries(ctx, time.Now()); err != nil {
+			log.Printf("Warning: failed to purge expired cache entries: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired cache entries", n)
+		}
+	}
+	responseCache := service.NewResponseCache(cacheSize, persistentCache)
+
+	// Outbound rate limiting and retries
+	rateLimiter := service.NewRateLimiter(
+		envFloat("STEAM_RATE_LIMIT", 10),
+		envInt("STEAM_RATE_BURST", 20),
+	)
+	retryPolicy := service.DefaultRetryPolicy
+	retryPolicy.MaxRetries = envInt("STEAM_MAX_RETRIES", retryPolicy.MaxRetries)
+	retryPolicy.BaseDelay = envDuration("STEAM_RETRY_BASE_DELAY", retryPolicy.BaseDelay)
+	retryPolicy.MaxDelay = envDuration("STEAM_RETRY_MAX_DELAY", retryPolicy.MaxDelay)
+	retryPolicy.Budget = envDuration("STEAM_RETRY_BUDGET", retryPolicy.Budget)
+
+	// The storefront allows far fewer requests than the Web API (~200 per 5 minutes).
+	storeRateLimiter := service.NewRateLimiter(
+		envFloat("STEAM_STORE_RATE_LIMIT", 0.5),
+		envInt("STEAM_STORE_RATE_BURST", 5),
+	)
+
 	// Init Services
-	steamClient := service.NewSteamClient(apiKey)
+	clientOpts := []service.ClientOption{
+		service.WithCache(responseCache),
+		service.WithRateLimiter(rateLimiter),
+		service.WithStoreRateLimiter(storeRateLimiter),
+		service.WithRetryPolicy(retryPolicy),
+	}
+	if v := os.Getenv("STEAM_API_URL"); v != "" {
+		clientOpts = append(clientOpts, service.WithBaseURL(v))
+	}
+	if v := os.Getenv("STEAM_STORE_URL"); v != "" {
+		clientOpts = append(clientOpts, service.WithStoreBaseURL(v))
+	}
+	steamClient := service.NewSteamClient(apiKey, clientOpts...)
 	dataService := service.NewDataService(s, steamClient)
+	historyService := service.NewHistoryService(s, dataService)
+	transferService := service.NewTransferService(s, s, steamClient)
+	catalogService := service.NewCatalogService(s, s, steamClient)
+	catalogService.RefreshAfter = envDuration("CATALOG_REFRESH_AFTER", catalogService.RefreshAfter)
+	catalogPollInterval := envDuration("CATALOG_POLL_INTERVAL", time.Minute)
+	goBackground(func(ctx context.Context) { catalogService.Run(ctx, catalogPollInterval) })
+	syncService := service.NewAchievementSyncService(s, steamClient)
+	syncService.Workers = envInt("ACHIEVEMENT_SYNC_WORKERS", syncService.Workers)
+	jobQueue := service.NewJobQueue(s)
+	jobQueue.Workers = envInt("JOB_WORKERS", jobQueue.Workers)
+	jobQueue.MaxAttempts = envInt("JOB_MAX_ATTEMPTS", jobQueue.MaxAttempts)
+	jobQueue.Retention = envDuration("JOB_RETENTION", jobQueue.Retention)
+	jobQueue.Register(service.AchievementSyncJob, syncService.RunJob)
+	goBackground(jobQueue.Run)
+	libraryService := service.NewLibraryService(s)
+	playtimeService := service.NewPlaytimeService(s)
+	playtimeService.CompactAfter = envDuration("PLAYTIME_COMPACT_AFTER", playtimeService.CompactAfter)
+	scheduler := service.NewScheduler(s, steamClient, libraryService, playtimeService, jobQueue)
+	scheduler.Interval = envDuration("SYNC_INTERVAL", scheduler.Interval)
+	scheduler.Jitter = envDuration("SYNC_JITTER", scheduler.Jitter)
+	scheduler.Concurrency = envInt("SYNC_CONCURRENCY", scheduler.Concurrency)
+	scheduler.PrivateBackoff = envDuration("SYNC_PRIVATE_BACKOFF", scheduler.PrivateBackoff)
+	if os.Getenv("SYNC_PAUSED") == "true" {
+		scheduler.Pause()
+	}
+	goBackground(scheduler.Run)
+
+	// Init Auth
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:" + port
+	}
+	openID := auth.NewOpenID(publicURL+"/", publicURL+"/api/auth/steam/callback")
+	if v := os.Getenv("OPENID_ENDPOINT"); v != "" {
+		openID.Endpoint = v
+	}
+	sessionSecret := []byte(os.Getenv("SESSION_SECRET"))
+	if len(sessionSecret) == 0 {
+		log.Println("Warning: SESSION_SECRET not set; using a random secret, sessions will not survive a restart.")
+		sessionSecret = make([]byte, 32)
+		if _, err := rand.Read(sessionSecret); err != nil {
+			log.Fatalf("Failed to generate session secret: %v", err)
+		}
+	}
+	tokens := auth.NewTokenIssuer(sessionSecret, envDuration("SESSION_TTL", 30*24*time.Hour))
+	tokens.RevokeWith(s.ErasedAt)
+	// Erasure receipts must stay verifiable after a restart, so they can have
+	// their own secret; without one they use the session secret.
+	receiptSecret := []byte(os.Getenv("RECEIPT_SECRET"))
+	if len(receiptSecret) == 0 {
+		receiptSecret = sessionSecret
+	}
+	accountService := service.NewAccountService(s, responseCache, auth.NewReceiptSigner(receiptSecret))
 
 	// Init Handlers
 	steamHandler := handlers.NewSteamHandler(steamClient)
 	dataHandler := handlers.NewDataHandler(dataService)
-	authHandler := handlers.NewAuthHandler(dataService)
+	historyHandler := handlers.NewHistoryHandler(historyService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	transferHandler := handlers.NewTransferHandler(transferService)
+	catalogHandler := handlers.NewCatalogHandler(catalogService, steamClient)
+	syncHandler := handlers.NewSyncHandler(syncService, jobQueue)
+	jobHandler := handlers.NewJobHandler(jobQueue, tokens)
+	libraryHandler := handlers.NewLibraryHandler(libraryService)
+	playtimeHandler := handlers.NewPlaytimeHandler(playtimeService)
+	adminHandler := handlers.NewAdminHandler(scheduler)
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	authHandler := handlers.NewAuthHandler(dataService, openID, tokens, os.Getenv("AUTH_APP_REDIRECT"))
+
+	// Per-route request deadlines
+	timeouts := handlers.RouteTimeouts{
+		Default: envDuration("REQUEST_TIMEOUT", 15*time.Second),
+		Routes: map[string]time.Duration{
+			"/api/steam/friends/":      30 * time.Second,
+			"/api/steam/achievements/": 20 * time.Second,
+			"/api/data/":               5 * time.Second,
+			// Imports and exports cover a user's whole collection at once.
+			"/api/data/{steamId}/export": time.Minute,
+			"/api/data/{steamId}/import": 2 * time.Minute,
+		},
+	}
+	if v := os.Getenv("ROUTE_TIMEOUTS"); v != "" {
+		overrides, err := handlers.ParseRouteTimeouts(v)
+		if err != nil {
+			log.Fatalf("Invalid ROUTE_TIMEOUTS: %v", err)
+		}
+		for pattern, d := range overrides {
+			timeouts.Routes[pattern] = d
+		}
+	}
 
 	// Routing
 	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, handlers.WithDeadline(timeouts.For(pattern), h))
+	}
+	// Steam routes take /{steamId}/... and also accept vanity names and profile links.
+	handleSteam := func(pattern string, h http.HandlerFunc) {
+		handle(pattern, handlers.ValidateSteamID(pattern, true, h))
+	}
 
 	// Steam Endpoints
-	mux.HandleFunc("/api/steam/user/", steamHandler.GetUserSummary)
-	mux.HandleFunc("/api/steam/games/", steamHandler.GetOwnedGames)
-	mux.HandleFunc("/api/steam/achievements/", steamHandler.GetPlayerAchievements)
-	mux.HandleFunc("/api/steam/friends/", steamHandler.GetFriendList)
-	mux.HandleFunc("/api/steam/recently-played/", steamHandler.GetRecentlyPlayedGames)
-	mux.HandleFunc("/api/steam/level/", steamHandler.GetSteamLevel)
-	mux.HandleFunc("/api/steam/bans/", steamHandler.GetPlayerBans)
-	mux.HandleFunc("/api/steam/badges/", steamHandler.GetBadges)
+	handleSteam("/api/steam/user/", steamHandler.GetUserSummary)
+	handleSteam("/api/steam/games/", steamHandler.GetOwnedGames)
+	handleSteam("/api/steam/achievements/", steamHandler.GetPlayerAchievements)
+	handleSteam("/api/steam/stats/", steamHandler.GetPlayerStats)
+	handleSteam("/api/steam/friends/", steamHandler.GetFriendList)
+	handleSteam("/api/steam/recently-played/", steamHandler.GetRecentlyPlayedGames)
+	handleSteam("/api/steam/level/", steamHandler.GetSteamLevel)
+	handleSteam("/api/steam/bans/", steamHandler.GetPlayerBans)
+	handleSteam("/api/steam/badges/", steamHandler.GetBadges)
+	handle("/api/steam/client-stats", steamHandler.GetClientStats)
+
+	// Catalog Endpoints
+	handle("/api/catalog/", catalogHandler.GetCatalogEntry)
+	handle("/api/library/", handlers.ValidateSteamID("/api/library/", false, catalogHandler.GetLibrary))
+
+	// Sync Endpoints
+	handle("/api/sync/achievements/", handlers.ValidateSteamID("/api/sync/achievements/", false,
+		handlers.RequireOwner(tokens, syncHandler.HandleAchievementSync)))
+	handle("/api/jobs/", jobHandler.GetJob)
+
+	// Admin Endpoints
+	handle("/api/admin/scheduler", handlers.RequireAdmin(adminToken, adminHandler.HandleScheduler))
+	handle("/api/admin/scheduler/", handlers.RequireAdmin(adminToken, adminHandler.HandleScheduler))
 
 	// Auth Endpoints
-	mux.HandleFunc("/api/auth/login", authHandler.HandleLogin)
+	handle("/api/auth/login", authHandler.HandleLogin)
+	handle("/api/auth/steam", authHandler.SteamLogin)
+	handle("/api/auth/steam/callback", authHandler.SteamCallback)
+	handle("/api/auth/session", authHandler.Session)
+	handle("/api/erasure-receipts/verify", accountHandler.VerifyReceipt)
 
 	// Data Endpoints
+	// Revisions carry the client's IP and User-Agent, so only the owner may read them.
+	historyRoute := handlers.RequirePrivate(tokens, historyHandler.HandleHistory)
 	// Data Endpoints with User Context
-	mux.HandleFunc("/api/data/", func(w http.ResponseWriter, r *http.Request) {
+	dataRoute := func(h http.HandlerFunc) http.HandlerFunc {
+		return handlers.WithClientInfo(handlers.ValidateSteamID("/api/data/", false, handlers.RequireOwner(tokens, h)))
+	}
+	handle("/api/data/{steamId}/export", dataRoute(transferHandler.Export))
+	handle("/api/data/{steamId}/import", dataRoute(transferHandler.Import))
+	handle("/api/data/", dataRoute(func(w http.ResponseWriter, r *http.Request) {
 		// Pattern expected: /api/data/{steamId}/games or /api/data/{steamId}/games/{appId}
 		// We can detect if it's a list or item based on trailing segments or simply by attempting item handler details.
 		
 		// Simplest dispatch: Check suffix or path parts
 		path := r.URL.Path
+		if rest := strings.Trim(strings.TrimPrefix(path, "/api/data/"), "/"); !strings.Contains(rest, "/") {
+			accountHandler.EraseAccount(w, r)
+			return
+		}
+		if parts := strings.Split(strings.TrimPrefix(path, "/api/data/"), "/"); len(parts) > 1 && parts[1] == "playtime" {
+			playtimeHandler.HandlePlaytime(w, r)
+			return
+		}
+		if strings.HasSuffix(strings.TrimSuffix(path, "/"), "/library/changes") {
+			libraryHandler.GetChanges(w, r)
+			return
+		}
 		// Check if it ends in "/games" or "/games/"
 		if len(path) > 6 && (path[len(path)-6:] == "/games" || path[len(path)-7:] == "/games/") {
              dataHandler.GetAllGameData(w, r)
              return
 		}
 		
+		if parts := strings.Split(strings.TrimPrefix(path, "/api/data/"), "/"); len(parts) > 3 && parts[1] == "games" && parts[3] == "history" {
+			historyRoute(w, r)
+			return
+		}
+
 		dataHandler.HandleGameData(w, r)
-	})
+	}))
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s...", port)
+		serverErr <- server.ListenAndServe()
+	}()
 
-	log.Printf("Server starting on port %s...", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting requests, let in-flight ones finish, then wait for the
+	// background workers before the deferred store Close.
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: HTTP shutdown: %v", err)
+	}
+	background.Wait()
+	log.Println("Server stopped")
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return n
+}
+
+func envFloat(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return f
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
 	}
+	return d
 }
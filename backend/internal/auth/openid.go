@@ -0,0 +1,238 @@
+// Package auth implements Steam sign-in through OpenID 2.0 and the signed
+// session tokens the API hands out afterwards.
+package auth
+
+import (
+	"backend/internal/steamid"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SteamOpenIDEndpoint is Steam's OpenID 2.0 provider endpoint.
+const SteamOpenIDEndpoint = "https://steamcommunity.com/openid/login"
+
+const (
+	openIDNamespace  = "http://specs.openid.net/auth/2.0"
+	identifierSelect = "http://specs.openid.net/auth/2.0/identifier_select"
+)
+
+// ErrVerificationFailed is returned when an OpenID assertion cannot be verified.
+var ErrVerificationFailed = errors.New("openid verification failed")
+
+var claimedIDPattern = regexp.MustCompile(`^https?://steamcommunity\.com/openid/id/(\d+)$`)
+
+// signedFields must all be covered by the provider's signature, otherwise
+// they could be swapped without invalidating the assertion.
+var signedFields = []string{"op_endpoint", "claimed_id", "identity", "return_to", "response_nonce", "assoc_handle"}
+
+// nonceMaxAge is how far a response_nonce's timestamp may be from now. Seen
+// nonces are remembered for that long, so an assertion can't be replayed.
+const nonceMaxAge = 5 * time.Minute
+
+// OpenID drives the Steam OpenID 2.0 sign-in flow in stateless mode: the
+// provider itself confirms each assertion through check_authentication.
+type OpenID struct {
+	// Endpoint is the provider URL. It defaults to SteamOpenIDEndpoint and can be
+	// pointed at a local stand-in provider for testing.
+	Endpoint string
+	// Realm is the trust root shown to the user, e.g. https://tracker.example.com/.
+	Realm string
+	// ReturnTo is the callback URL the provider redirects back to.
+	ReturnTo   string
+	HTTPClient *http.Client
+
+	now    func() time.Time
+	nonces nonceSet
+}
+
+func NewOpenID(realm, returnTo string) *OpenID {
+	return &OpenID{
+		Endpoint: SteamOpenIDEndpoint,
+		Realm:    realm,
+		ReturnTo: returnTo,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		now: time.Now,
+	}
+}
+
+// AuthURL returns the provider URL to redirect the user to.
+func (o *OpenID) AuthURL() string {
+	q := url.Values{}
+	q.Set("openid.ns", openIDNamespace)
+	q.Set("openid.mode", "checkid_setup")
+	q.Set("openid.return_to", o.ReturnTo)
+	q.Set("openid.realm", o.Realm)
+	q.Set("openid.identity", identifierSelect)
+	q.Set("openid.claimed_id", identifierSelect)
+	return o.Endpoint + "?" + q.Encode()
+}
+
+// Verify checks the assertion carried by the callback query string and returns
+// the signed-in user's SteamID. Each assertion is accepted only once.
+func (o *OpenID) Verify(ctx context.Context, query url.Values) (steamid.ID, error) {
+	if query.Get("openid.mode") != "id_res" {
+		return 0, fmt.Errorf("%w: unexpected mode %q", ErrVerificationFailed, query.Get("openid.mode"))
+	}
+	if !o.matchesReturnTo(query.Get("openid.return_to")) {
+		return 0, fmt.Errorf("%w: return_to mismatch", ErrVerificationFailed)
+	}
+	if query.Get("openid.op_endpoint") != o.Endpoint {
+		return 0, fmt.Errorf("%w: unexpected provider endpoint", ErrVerificationFailed)
+	}
+	signed := make(map[string]bool)
+	for _, field := range strings.Split(query.Get("openid.signed"), ",") {
+		signed[field] = true
+	}
+	for _, field := range signedFields {
+		if !signed[field] {
+			return 0, fmt.Errorf("%w: %s is not signed", ErrVerificationFailed, field)
+		}
+	}
+
+	nonce := query.Get("openid.response_nonce")
+	now := o.now()
+	issued, err := nonceTime(nonce)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+	if age := now.Sub(issued); age >= nonceMaxAge || age <= -nonceMaxAge {
+		return 0, fmt.Errorf("%w: response_nonce is %v old", ErrVerificationFailed, age.Round(time.Second))
+	}
+	if o.nonces.seen(nonce, now) {
+		return 0, fmt.Errorf("%w: response_nonce already used", ErrVerificationFailed)
+	}
+
+	claimed := query.Get("openid.claimed_id")
+	m := claimedIDPattern.FindStringSubmatch(claimed)
+	if m == nil || query.Get("openid.identity") != claimed {
+		return 0, fmt.Errorf("%w: unexpected claimed_id %q", ErrVerificationFailed, claimed)
+	}
+	id, err := steamid.Parse(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	if err := o.checkAuthentication(ctx, query); err != nil {
+		return 0, err
+	}
+	// Claim the nonce only once the provider has vouched for it, so forged
+	// assertions can't use up nonces; a concurrent replay loses here.
+	if !o.nonces.add(nonce, issued.Add(nonceMaxAge), now) {
+		return 0, fmt.Errorf("%w: response_nonce already used", ErrVerificationFailed)
+	}
+	return id, nil
+}
+
+// matchesReturnTo reports whether returnTo is exactly the configured callback URL.
+func (o *OpenID) matchesReturnTo(returnTo string) bool {
+	got, err := url.Parse(returnTo)
+	if err != nil {
+		return false
+	}
+	want, err := url.Parse(o.ReturnTo)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(got.Scheme, want.Scheme) &&
+		strings.EqualFold(got.Host, want.Host) &&
+		got.Path == want.Path &&
+		got.RawQuery == want.RawQuery &&
+		got.User == nil && got.Fragment == ""
+}
+
+// nonceTime reads the UTC timestamp that starts every OpenID 2.0 response
+// nonce, e.g. 2024-03-01T12:00:00Zab12.
+func nonceTime(nonce string) (time.Time, error) {
+	const layout = "2006-01-02T15:04:05Z"
+	if len(nonce) < len(layout) {
+		return time.Time{}, fmt.Errorf("malformed response_nonce %q", nonce)
+	}
+	t, err := time.Parse(layout, nonce[:len(layout)])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed response_nonce %q", nonce)
+	}
+	return t, nil
+}
+
+// nonceSet remembers accepted response nonces until they are too old to
+// pass the age check anyway. It is per process, so replicas behind a load
+// balancer each keep their own.
+type nonceSet struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func (n *nonceSet) seen(nonce string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	exp, ok := n.expires[nonce]
+	return ok && now.Before(exp)
+}
+
+// add records nonce until expires and reports whether it was new.
+func (n *nonceSet) add(nonce string, expires, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.expires == nil {
+		n.expires = make(map[string]time.Time)
+	}
+	for k, exp := range n.expires {
+		if !now.Before(exp) {
+			delete(n.expires, k)
+		}
+	}
+	if _, ok := n.expires[nonce]; ok {
+		return false
+	}
+	n.expires[nonce] = expires
+	return true
+}
+
+// checkAuthentication asks the provider to confirm the signature on the assertion.
+func (o *OpenID) checkAuthentication(ctx context.Context, query url.Values) error {
+	form := url.Values{}
+	for k, v := range query {
+		if strings.HasPrefix(k, "openid.") {
+			form[k] = v
+		}
+	}
+	form.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: provider returned status %d", ErrVerificationFailed, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	// The response is key:value lines; we only care about is_valid.
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(line) == "is_valid:true" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: provider rejected the assertion", ErrVerificationFailed)
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testReturnTo = "https://tracker.example.com/api/auth/steam/callback"
+	testClaimed  = "https://steamcommunity.com/openid/id/76561197960287930"
+)
+
+// provider is a stand-in OpenID provider. It signs assertions with an HMAC
+// over the signed fields, and check_authentication recomputes it, as a real
+// provider does with its association secret.
+type provider struct {
+	*httptest.Server
+	key    []byte
+	checks atomic.Int32
+}
+
+func newProvider(t *testing.T) *provider {
+	p := &provider{key: []byte("provider association secret")}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("openid.mode") != "check_authentication" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		p.checks.Add(1)
+		valid := hmac.Equal([]byte(r.PostForm.Get("openid.sig")), []byte(p.sign(r.PostForm)))
+		fmt.Fprintf(w, "ns:%s\nis_valid:%t\n", openIDNamespace, valid)
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// sign computes the signature over the fields listed in openid.signed.
+func (p *provider) sign(q url.Values) string {
+	var b strings.Builder
+	for _, field := range strings.Split(q.Get("openid.signed"), ",") {
+		fmt.Fprintf(&b, "%s:%s\n", field, q.Get("openid."+field))
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// assertion returns a signed callback query for the given nonce time.
+func (p *provider) assertion(issued time.Time, nonceSuffix string) url.Values {
+	q := url.Values{}
+	q.Set("openid.ns", openIDNamespace)
+	q.Set("openid.mode", "id_res")
+	q.Set("openid.op_endpoint", p.URL)
+	q.Set("openid.claimed_id", testClaimed)
+	q.Set("openid.identity", testClaimed)
+	q.Set("openid.return_to", testReturnTo)
+	q.Set("openid.response_nonce", issued.UTC().Format("2006-01-02T15:04:05Z")+nonceSuffix)
+	q.Set("openid.assoc_handle", "1234567890")
+	q.Set("openid.signed", "signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle")
+	q.Set("openid.sig", p.sign(q))
+	return q
+}
+
+func newTestOpenID(p *provider, now time.Time) *OpenID {
+	o := NewOpenID("https://tracker.example.com/", testReturnTo)
+	o.Endpoint = p.URL
+	o.now = func() time.Time { return now }
+	return o
+}
+
+func TestVerifyValidAssertion(t *testing.T) {
+	p := newProvider(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	o := newTestOpenID(p, now)
+
+	id, err := o.Verify(context.Background(), p.assertion(now.Add(-10*time.Second), "abc"))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if id.String() != "76561197960287930" {
+		t.Errorf("Verify = %s, want 76561197960287930", id)
+	}
+	if n := p.checks.Load(); n != 1 {
+		t.Errorf("provider saw %d check_authentication calls, want 1", n)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		// edit changes the signed assertion before it reaches Verify.
+		edit func(p *provider, q url.Values)
+		// checked reports whether the provider should still be asked.
+		checked bool
+	}{
+		{"provider says is_valid:false", func(p *provider, q url.Values) {
+			q.Set("openid.sig", "forged")
+		}, true},
+		{"tampered claimed_id", func(p *provider, q url.Values) {
+			other := "https://steamcommunity.com/openid/id/76561197960287931"
+			q.Set("openid.claimed_id", other)
+			q.Set("openid.identity", other)
+		}, true},
+		{"claimed_id not signed", func(p *provider, q url.Values) {
+			other := "https://steamcommunity.com/openid/id/76561197960287931"
+			q.Set("openid.signed", "signed,op_endpoint,return_to,response_nonce,assoc_handle")
+			q.Set("openid.claimed_id", other)
+			q.Set("openid.identity", other)
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"response_nonce not signed", func(p *provider, q url.Values) {
+			q.Set("openid.signed", "signed,op_endpoint,claimed_id,identity,return_to,assoc_handle")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"wrong op_endpoint", func(p *provider, q url.Values) {
+			q.Set("openid.op_endpoint", "https://evil.example.com/openid/login")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"return_to with extra path", func(p *provider, q url.Values) {
+			q.Set("openid.return_to", testReturnTo+"/../../evil")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"return_to on another host", func(p *provider, q url.Values) {
+			q.Set("openid.return_to", "https://tracker.example.com.evil.net/api/auth/steam/callback")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"return_to with a query", func(p *provider, q url.Values) {
+			q.Set("openid.return_to", testReturnTo+"?next=https://evil.example.com")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"stale nonce", func(p *provider, q url.Values) {
+			q.Set("openid.response_nonce", now.Add(-10*time.Minute).Format("2006-01-02T15:04:05Z")+"abc")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"nonce from the future", func(p *provider, q url.Values) {
+			q.Set("openid.response_nonce", now.Add(10*time.Minute).Format("2006-01-02T15:04:05Z")+"abc")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"malformed nonce", func(p *provider, q url.Values) {
+			q.Set("openid.response_nonce", "yesterday")
+			q.Set("openid.sig", p.sign(q))
+		}, false},
+		{"wrong mode", func(p *provider, q url.Values) {
+			q.Set("openid.mode", "cancel")
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			o := newTestOpenID(p, now)
+			q := p.assertion(now, "abc")
+			tt.edit(p, q)
+
+			if _, err := o.Verify(context.Background(), q); !errors.Is(err, ErrVerificationFailed) {
+				t.Fatalf("Verify error = %v, want ErrVerificationFailed", err)
+			}
+			if checked := p.checks.Load() > 0; checked != tt.checked {
+				t.Errorf("provider asked: %v, want %v", checked, tt.checked)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplayedNonce(t *testing.T) {
+	p := newProvider(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	o := newTestOpenID(p, now)
+	q := p.assertion(now, "abc")
+
+	if _, err := o.Verify(context.Background(), q); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if _, err := o.Verify(context.Background(), q); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("replayed Verify error = %v, want ErrVerificationFailed", err)
+	}
+	// Later the nonce is too old to pass the age check, so forgetting it is safe.
+	o.now = func() time.Time { return now.Add(nonceMaxAge) }
+	if _, err := o.Verify(context.Background(), q); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("Verify after the nonce expired error = %v, want ErrVerificationFailed", err)
+	}
+
+	// Another assertion issued in the same second has its own nonce.
+	o.now = func() time.Time { return now }
+	if _, err := o.Verify(context.Background(), p.assertion(now, "abd")); err != nil {
+		t.Errorf("Verify with a fresh nonce: %v", err)
+	}
+}
+
+func TestNonceSetForgetsExpired(t *testing.T) {
+	var n nonceSet
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if !n.add("a", now.Add(time.Minute), now) {
+		t.Fatal("add of a new nonce reported it as seen")
+	}
+	if n.add("a", now.Add(time.Minute), now) {
+		t.Error("add of a seen nonce reported it as new")
+	}
+	later := now.Add(2 * time.Minute)
+	n.add("b", later.Add(time.Minute), later)
+	if _, ok := n.expires["a"]; ok {
+		t.Error("expired nonce was kept")
+	}
+}
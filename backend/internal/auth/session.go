@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"backend/internal/steamid"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for session tokens that are malformed, forged or expired.
+var ErrInvalidToken = errors.New("invalid session token")
+
+// Session is the payload of a session token.
+type Session struct {
+	SteamID   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenIssuer creates and checks HMAC-SHA256 signed session tokens of the form
+// base64url(payload) "." base64url(signature).
+type TokenIssuer struct {
+	secret  []byte
+	ttl     time.Duration
+	revoked RevocationFunc
+	now     func() time.Time
+}
+
+// RevocationFunc returns the time up to which sessions for steamID have been
+// revoked, or the zero time if they haven't been.
+type RevocationFunc func(ctx context.Context, steamID string) (time.Time, error)
+
+func NewTokenIssuer(secret []byte, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// Issue returns a token for id and its expiry time.
+func (t *TokenIssuer) Issue(id steamid.ID) (string, time.Time, error) {
+	now := t.now()
+	expires := now.Add(t.ttl)
+	payload, err := json.Marshal(Session{
+		SteamID:   id.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + t.sign(encoded), expires, nil
+}
+
+// RevokeWith makes Verify refuse sessions issued at or before the time
+// revoked returns for their SteamID, say because the account was erased.
+// Sessions are issued to the second, so one issued in the same second as the
+// revocation is refused too.
+func (t *TokenIssuer) RevokeWith(revoked RevocationFunc) {
+	t.revoked = revoked
+}
+
+// Verify checks the signature, expiry and revocation of token and returns its session.
+func (t *TokenIssuer) Verify(ctx context.Context, token string) (*Session, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(t.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var s Session
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if t.now().Unix() >= s.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+	if _, err := steamid.Parse(s.SteamID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if t.revoked != nil {
+		revokedAt, err := t.revoked(ctx, s.SteamID)
+		if err != nil {
+			return nil, err
+		}
+		if !revokedAt.IsZero() && s.IssuedAt <= revokedAt.Unix() {
+			return nil, ErrInvalidToken
+		}
+	}
+	return &s, nil
+}
+
+func (t *TokenIssuer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"backend/internal/steamid"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestIssuer(now *time.Time) *TokenIssuer {
+	t := NewTokenIssuer([]byte("session secret"), time.Hour)
+	t.now = func() time.Time { return *now }
+	return t
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issuer := newTestIssuer(&now)
+
+	token, expires, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Issue expiry = %v, want %v", expires, now.Add(time.Hour))
+	}
+	s, err := issuer.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if s.SteamID != "76561197960287930" || s.IssuedAt != now.Unix() || s.ExpiresAt != expires.Unix() {
+		t.Errorf("Verify = %+v", s)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issuer := newTestIssuer(&now)
+	token, _, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	now = now.Add(time.Hour - time.Second)
+	if _, err := issuer.Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify a second before expiry: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := issuer.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify at expiry error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestTokenRevocation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issuer := newTestIssuer(&now)
+	revokedAt := map[string]time.Time{}
+	issuer.RevokeWith(func(ctx context.Context, steamID string) (time.Time, error) {
+		return revokedAt[steamID], nil
+	})
+
+	before, _, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	other, _, err := issuer.Issue(steamid.ID(76561197960287931))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	now = now.Add(time.Minute)
+	revokedAt["76561197960287930"] = now
+	sameSecond, _, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	now = now.Add(time.Second)
+	after, _, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	for name, token := range map[string]string{"issued before": before, "issued the same second": sameSecond} {
+		if _, err := issuer.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s revocation: Verify error = %v, want ErrInvalidToken", name, err)
+		}
+	}
+	for name, token := range map[string]string{"issued after revocation": after, "another user's": other} {
+		if _, err := issuer.Verify(context.Background(), token); err != nil {
+			t.Errorf("%s: Verify: %v", name, err)
+		}
+	}
+
+	failure := errors.New("store down")
+	issuer.RevokeWith(func(ctx context.Context, steamID string) (time.Time, error) {
+		return time.Time{}, failure
+	})
+	if _, err := issuer.Verify(context.Background(), after); !errors.Is(err, failure) {
+		t.Errorf("Verify with a failing revocation check error = %v, want %v", err, failure)
+	}
+}
+
+func TestTokenTampering(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issuer := newTestIssuer(&now)
+	token, _, err := issuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	encoded, sig, _ := strings.Cut(token, ".")
+
+	// Same signature over a payload naming someone else.
+	forged := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"sub":"76561197960287931","iat":1700000000,"exp":1700003600}`))
+	// Same payload with a far later expiry.
+	extended := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"sub":"76561197960287930","iat":1700000000,"exp":1900000000}`))
+	otherIssuer := NewTokenIssuer([]byte("another secret"), time.Hour)
+	otherIssuer.now = issuer.now
+	otherToken, _, err := otherIssuer.Issue(steamid.ID(76561197960287930))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", encoded},
+		{"payload swapped", forged + "." + sig},
+		{"expiry extended", extended + "." + sig},
+		{"signature truncated", encoded + "." + sig[:len(sig)-1]},
+		{"signature from another secret", otherToken},
+		{"not base64", "!!!." + sig},
+	}
+	for _, tt := range tests {
+		if _, err := issuer.Verify(context.Background(), tt.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify error = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
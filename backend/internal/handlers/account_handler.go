@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type AccountHandler struct {
+	accounts *service.AccountService
+}
+
+func NewAccountHandler(accounts *service.AccountService) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// EraseAccount deletes everything held about the signed-in user and returns a
+// signed receipt. It must run inside RequireOwner.
+func (h *AccountHandler) EraseAccount(w http.ResponseWriter, r *http.Request) {
+	// Pattern: DELETE /api/data/{steamId}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := requestSession(r); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.accounts.Erase(r.Context(), id.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// VerifyReceipt checks a receipt token from EraseAccount, posted as
+// {"token": "..."}, and returns the receipt it carries.
+func (h *AccountHandler) VerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	// Pattern: POST /api/erasure-receipts/verify
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Token == "" {
+		http.Error(w, "Invalid Body", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := h.accounts.VerifyReceipt(body.Token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
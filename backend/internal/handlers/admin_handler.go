@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type AdminHandler struct {
+	scheduler *service.Scheduler
+}
+
+func NewAdminHandler(scheduler *service.Scheduler) *AdminHandler {
+	return &AdminHandler{scheduler: scheduler}
+}
+
+// HandleScheduler reports the background scheduler's state on GET and
+// pauses or resumes it on POST /pause and /resume.
+func (h *AdminHandler) HandleScheduler(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/admin/scheduler[/pause|/resume]
+	action := extractID(r, "/api/admin/scheduler/")
+
+	switch {
+	case r.Method == http.MethodGet && action == "":
+	case r.Method == http.MethodPost && action == "pause":
+		h.scheduler.Pause()
+	case r.Method == http.MethodPost && action == "resume":
+		h.scheduler.Resume()
+	case action != "" && action != "pause" && action != "resume":
+		http.NotFound(w, r)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
@@ -1,23 +1,46 @@
 package handlers
 
 import (
+	"backend/internal/auth"
+	"backend/internal/models"
 	"backend/internal/service"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 type AuthHandler struct {
 	service *service.DataService
+	openID  *auth.OpenID
+	tokens  *auth.TokenIssuer
+	// appRedirect, when set, is where the OpenID callback sends the browser
+	// with the session token in the fragment, so the app can pick it up.
+	appRedirect string
 }
 
-func NewAuthHandler(service *service.DataService) *AuthHandler {
-	return &AuthHandler{service: service}
+func NewAuthHandler(service *service.DataService, openID *auth.OpenID, tokens *auth.TokenIssuer, appRedirect string) *AuthHandler {
+	return &AuthHandler{
+		service:     service,
+		openID:      openID,
+		tokens:      tokens,
+		appRedirect: appRedirect,
+	}
 }
 
 type LoginRequest struct {
 	SteamID string `json:"steamId"`
 }
 
+// SessionResponse is returned after a successful Steam sign-in.
+type SessionResponse struct {
+	Token     string            `json:"token"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	User      *models.SteamUser `json:"user"`
+}
+
+// HandleLogin looks up a Steam profile by SteamID, profile link or vanity
+// name and returns its summary, without registering the user.
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -35,9 +58,15 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.service.RegisterOrUpdateUser(req.SteamID)
+	steamID, err := h.service.ResolveSteamID(r.Context(), req.SteamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := h.service.LookupUser(r.Context(), steamID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	if user == nil {
@@ -48,3 +77,71 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
+
+// SteamLogin redirects the browser to Steam's OpenID sign-in page.
+func (h *AuthHandler) SteamLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.Redirect(w, r, h.openID.AuthURL(), http.StatusFound)
+}
+
+// SteamCallback verifies the OpenID assertion Steam redirected back with and
+// issues a session token for the signed-in SteamID.
+func (h *AuthHandler) SteamCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := h.openID.Verify(r.Context(), r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := h.service.RegisterOrUpdateUser(r.Context(), id.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found on Steam", http.StatusNotFound)
+		return
+	}
+
+	token, expires, err := h.tokens.Issue(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if h.appRedirect != "" {
+		fragment := url.Values{}
+		fragment.Set("token", token)
+		fragment.Set("steamId", id.String())
+		http.Redirect(w, r, h.appRedirect+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SessionResponse{Token: token, ExpiresAt: expires, User: user})
+}
+
+// Session reports who the bearer token belongs to.
+func (h *AuthHandler) Session(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, err := h.tokens.Verify(r.Context(), bearerToken(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CatalogHandler struct {
+	catalog *service.CatalogService
+	client  *service.SteamClient
+}
+
+func NewCatalogHandler(catalog *service.CatalogService, client *service.SteamClient) *CatalogHandler {
+	return &CatalogHandler{catalog: catalog, client: client}
+}
+
+// CatalogPendingResponse is returned with 202 Accepted for an app that is
+// queued for the background refresher but has not been fetched yet.
+type CatalogPendingResponse struct {
+	AppID   int  `json:"appId"`
+	Pending bool `json:"pending"`
+}
+
+// GetCatalogEntry serves stored metadata for apps in the catalog or in
+// someone's library; it answers 404 for any other app.
+func (h *CatalogHandler) GetCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/catalog/{appId}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appID, err := strconv.Atoi(extractID(r, "/api/catalog/"))
+	if err != nil || appID <= 0 {
+		http.Error(w, "Invalid App ID", http.StatusBadRequest)
+		return
+	}
+
+	meta, err := h.catalog.GetMetadata(r.Context(), appID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if meta == nil {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(CatalogPendingResponse{AppID: appID, Pending: true})
+		return
+	}
+	json.NewEncoder(w).Encode(meta)
+}
+
+// GetLibrary returns the user's owned games with catalog metadata attached.
+// ?genre= keeps only games tagged with that genre (case-insensitive).
+func (h *CatalogHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/library/{steamId}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	games, err := clientFor(r, h.client).GetOwnedGames(r.Context(), id.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	library, err := h.catalog.EnrichLibrary(r.Context(), games)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if genre := r.URL.Query().Get("genre"); genre != "" {
+		filtered := make([]models.LibraryGame, 0, len(library))
+		for _, g := range library {
+			if g.Metadata != nil && hasGenre(g.Metadata, genre) {
+				filtered = append(filtered, g)
+			}
+		}
+		library = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(library)
+}
+
+func hasGenre(meta *models.GameMetadata, genre string) bool {
+	for _, g := range meta.Genres {
+		if strings.EqualFold(g, genre) {
+			return true
+		}
+	}
+	return false
+}
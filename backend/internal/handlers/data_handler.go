@@ -3,8 +3,12 @@ package handlers
 import (
 	"backend/internal/models"
 	"backend/internal/service"
+	"backend/internal/store"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -27,7 +31,12 @@ func (h *DataHandler) HandleGameData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	steamID := parts[0]
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+	steamID := id.String()
 	appIDStr := parts[2]
 	
 	appID, err := strconv.Atoi(appIDStr)
@@ -38,9 +47,9 @@ func (h *DataHandler) HandleGameData(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		data, err := h.service.GetGameData(steamID, appID)
+		data, err := h.service.GetGameData(r.Context(), steamID, appID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 		if data == nil {
@@ -59,11 +68,18 @@ func (h *DataHandler) HandleGameData(w http.ResponseWriter, r *http.Request) {
 		}
 		data.AppID = appID // Ensure ID matches URL
 		
-		if err := h.service.SaveGameData(steamID, &data); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := h.service.SaveGameData(r.Context(), steamID, &data); err != nil {
+			writeError(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
+
+	case http.MethodDelete:
+		if err := h.service.DeleteGameData(r.Context(), steamID, appID); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 		
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -79,19 +95,115 @@ func (h *DataHandler) GetAllGameData(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
-	steamID := parts[0]
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+	steamID := id.String()
 	
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	data, err := h.service.GetAllGameData(steamID)
+	// Without filters the response stays a map keyed by app ID; any filter,
+	// sort or limit returns an ordered list instead.
+	if !hasGameDataQuery(r.URL.Query()) {
+		data, err := h.service.GetAllGameData(r.Context(), steamID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	q, err := parseGameDataQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := h.service.QueryGameData(r.Context(), steamID, q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
 }
+
+var gameDataQueryParams = []string{"status", "minRating", "maxRating", "favorite", "sort", "order", "limit", "offset"}
+
+func hasGameDataQuery(values url.Values) bool {
+	for _, name := range gameDataQueryParams {
+		if values.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+var gameDataSorts = map[string]store.GameDataSort{
+	"appId":     store.SortByAppID,
+	"rating":    store.SortByRating,
+	"playOrder": store.SortByPlayOrder,
+	"created":   store.SortByCreated,
+	"updated":   store.SortByUpdated,
+}
+
+// parseGameDataQuery reads ?status=backlog|1&minRating=&maxRating=&favorite=
+// &sort=rating&order=desc&limit=&offset=.
+func parseGameDataQuery(values url.Values) (store.GameDataQuery, error) {
+	var q store.GameDataQuery
+
+	if v := values.Get("status"); v != "" {
+		status, err := models.ParseLocalGameStatus(v)
+		if err != nil {
+			return q, errors.New("Invalid status")
+		}
+		q.Status = &status
+	}
+	for name, dst := range map[string]**float64{"minRating": &q.MinRating, "maxRating": &q.MaxRating} {
+		if v := values.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return q, fmt.Errorf("Invalid %s", name)
+			}
+			*dst = &f
+		}
+	}
+	if v := values.Get("favorite"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, errors.New("Invalid favorite")
+		}
+		q.Favorite = &b
+	}
+	if v := values.Get("sort"); v != "" {
+		sort, ok := gameDataSorts[v]
+		if !ok {
+			return q, errors.New("Invalid sort")
+		}
+		q.Sort = sort
+	}
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, errors.New("Invalid order")
+	}
+	for name, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if v := values.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return q, fmt.Errorf("Invalid %s", name)
+			}
+			*dst = n
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ErrorResponse is the JSON body returned for service-layer failures.
+// Code is stable and meant for the app to switch on; Message is for humans.
+type ErrorResponse struct {
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+// writeError maps a service-layer error to a status code and JSON body.
+func writeError(w http.ResponseWriter, err error) {
+	status, code, msg := http.StatusInternalServerError, "internal_error", "Internal server error"
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		status, code, msg = http.StatusUnauthorized, "invalid_token", "Sign in with Steam to continue"
+	case errors.Is(err, auth.ErrInvalidReceipt):
+		status, code, msg = http.StatusBadRequest, "invalid_receipt", "The receipt was not issued by this server or has been altered"
+	case errors.Is(err, auth.ErrVerificationFailed):
+		status, code, msg = http.StatusUnauthorized, "openid_verification_failed", "Steam sign-in could not be verified"
+	case errors.Is(err, service.ErrInvalidSteamID):
+		status, code, msg = http.StatusBadRequest, "invalid_steam_id", "Not a valid SteamID, profile link or vanity name"
+	case errors.Is(err, service.ErrProfileNotFound):
+		status, code, msg = http.StatusNotFound, "profile_not_found", "No Steam profile matches that name"
+	case errors.Is(err, service.ErrJobNotFound):
+		status, code, msg = http.StatusNotFound, "job_not_found", "No job with that ID"
+	case errors.Is(err, service.ErrUnknownApp):
+		status, code, msg = http.StatusNotFound, "unknown_app", "This app is not in the catalog"
+	case errors.Is(err, service.ErrRevisionNotFound):
+		status, code, msg = http.StatusNotFound, "revision_not_found", "No revision with that number for this game"
+	case errors.Is(err, service.ErrPrivateProfile):
+		status, code, msg = http.StatusForbidden, "private_profile", "This Steam profile or its game details are private"
+	case errors.Is(err, service.ErrNoStats):
+		status, code, msg = http.StatusNotFound, "no_stats", "This game has no stats or achievements"
+	case errors.Is(err, service.ErrRateLimited):
+		status, code, msg = http.StatusTooManyRequests, "rate_limited", "Steam is rate limiting requests, try again later"
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		status, code, msg = http.StatusServiceUnavailable, "upstream_unavailable", "Steam is temporarily unavailable"
+	case errors.Is(err, service.ErrUnauthorizedKey):
+		status, code, msg = http.StatusBadGateway, "upstream_unauthorized", "The server's Steam API key was rejected"
+	case errors.Is(err, context.DeadlineExceeded):
+		status, code, msg = http.StatusGatewayTimeout, "timeout", "The request timed out"
+	case errors.Is(err, context.Canceled):
+		// The client has gone away; nobody will read the response.
+		return
+	default:
+		var apiErr *service.SteamAPIError
+		if errors.As(err, &apiErr) {
+			status, code, msg = http.StatusBadGateway, "upstream_error", "Steam returned an unexpected error"
+		}
+	}
+
+	if status >= 500 {
+		log.Printf("request failed: %v", err)
+	}
+
+	var apiErr *service.SteamAPIError
+	if status == http.StatusTooManyRequests && errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+	writeJSONError(w, status, code, msg)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: msg})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteError(t *testing.T) {
+	apiErr := func(status int, kind error) error {
+		return fmt.Errorf("achievements for app 440: %w", &service.SteamAPIError{Path: "/ISteamUserStats/GetPlayerAchievements/v0001/", StatusCode: status, Kind: kind})
+	}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"private profile", fmt.Errorf("achievements for app 440: %w", service.ErrPrivateProfile), http.StatusForbidden, "private_profile"},
+		{"private profile from Steam", apiErr(http.StatusForbidden, service.ErrPrivateProfile), http.StatusForbidden, "private_profile"},
+		{"no stats", apiErr(http.StatusBadRequest, service.ErrNoStats), http.StatusNotFound, "no_stats"},
+		{"bad key", apiErr(http.StatusUnauthorized, service.ErrUnauthorizedKey), http.StatusBadGateway, "upstream_unauthorized"},
+		{"rate limited", apiErr(http.StatusTooManyRequests, service.ErrRateLimited), http.StatusTooManyRequests, "rate_limited"},
+		{"unavailable", apiErr(http.StatusServiceUnavailable, service.ErrUpstreamUnavailable), http.StatusServiceUnavailable, "upstream_unavailable"},
+		{"unclassified Steam error", apiErr(http.StatusBadRequest, nil), http.StatusBadGateway, "upstream_error"},
+		{"internal", errors.New("disk full"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if body.Code != tt.code || body.Message == "" {
+				t.Errorf("body = %+v, want error %q with a message", body, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteErrorRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, &service.SteamAPIError{StatusCode: http.StatusTooManyRequests, Kind: service.ErrRateLimited, RetryAfter: 1500 * time.Millisecond})
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
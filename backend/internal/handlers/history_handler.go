@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type HistoryHandler struct {
+	history *service.HistoryService
+}
+
+func NewHistoryHandler(history *service.HistoryService) *HistoryHandler {
+	return &HistoryHandler{history: history}
+}
+
+// HandleHistory lists a game's revisions (GET .../history) and restores one
+// (POST .../history/{revision}/restore). Both are for the owner only.
+func (h *HistoryHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/data/{steamId}/games/{appId}/history[/{revision}/restore]
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/data/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 || parts[1] != "games" || parts[3] != "history" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+	appID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		http.Error(w, "Invalid App ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 4:
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		revisions, err := h.history.History(r.Context(), id.String(), appID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(revisions)
+
+	case len(parts) == 6 && parts[5] == "restore":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		revision, err := strconv.ParseInt(parts[4], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+		data, err := h.history.Restore(r.Context(), id.String(), appID, revision)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
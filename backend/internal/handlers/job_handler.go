@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type JobHandler struct {
+	jobs   *service.JobQueue
+	tokens *auth.TokenIssuer
+}
+
+func NewJobHandler(jobs *service.JobQueue, tokens *auth.TokenIssuer) *JobHandler {
+	return &JobHandler{jobs: jobs, tokens: tokens}
+}
+
+// GetJob reports a queued job's status, progress and last error. Only the
+// job's user may see it; anyone else gets the same 404 as for an unknown ID.
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/jobs/{jobId}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, err := h.tokens.Verify(r.Context(), bearerToken(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	job, err := h.jobs.Get(r.Context(), extractID(r, "/api/jobs/"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if job.SteamID != session.SteamID {
+		writeError(w, service.ErrJobNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/internal/steamid"
+	"backend/internal/store"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	ownerSteamID = steamid.ID(76561197960287930)
+	otherSteamID = steamid.ID(76561197960287931)
+)
+
+func issueToken(t *testing.T, tokens *auth.TokenIssuer, id steamid.ID) string {
+	t.Helper()
+	token, _, err := tokens.Issue(id)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+func TestGetJobOnlyShowsTheOwnersJobs(t *testing.T) {
+	jobs := service.NewJobQueue(store.NewMemoryStore())
+	jobs.Register("test", func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+		return nil, nil
+	})
+	job, err := jobs.Enqueue(context.Background(), "test", ownerSteamID.String(), nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	tokens := auth.NewTokenIssuer([]byte("secret"), time.Hour)
+	h := NewJobHandler(jobs, tokens)
+
+	tests := []struct {
+		name   string
+		id     string
+		token  string
+		status int
+	}{
+		{"owner", job.ID, issueToken(t, tokens, ownerSteamID), http.StatusOK},
+		{"other user", job.ID, issueToken(t, tokens, otherSteamID), http.StatusNotFound},
+		{"unknown job", "missing", issueToken(t, tokens, ownerSteamID), http.StatusNotFound},
+		{"no session", job.ID, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+tt.id, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.GetJob(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got models.Job
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || got.ID != job.ID {
+				t.Errorf("body = %+v, %v; want job %s", got, err, job.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type LibraryHandler struct {
+	library *service.LibraryService
+}
+
+func NewLibraryHandler(library *service.LibraryService) *LibraryHandler {
+	return &LibraryHandler{library: library}
+}
+
+// GetChanges returns the library changes recorded after ?since= (RFC 3339
+// or Unix seconds); without it, all changes. It doesn't refresh the library;
+// the scheduler does that for registered users.
+func (h *LibraryHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/data/{steamId}/library/changes
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := parseSince(v)
+		if err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	events, err := h.library.Changes(r.Context(), id.String(), since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func parseSince(v string) (time.Time, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
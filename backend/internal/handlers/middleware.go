@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/internal/steamid"
+	"backend/internal/store"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RouteTimeouts holds request deadlines keyed by route pattern.
+type RouteTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// For returns the deadline configured for pattern, falling back to Default.
+func (t RouteTimeouts) For(pattern string) time.Duration {
+	if d, ok := t.Routes[pattern]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// ParseRouteTimeouts parses overrides of the form "/api/steam/friends/=30s,/api/data/=5s".
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	routes := make(map[string]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route timeout %q", part)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route timeout %q: %w", part, err)
+		}
+		routes[pattern] = d
+	}
+	return routes, nil
+}
+
+// WithDeadline bounds the request context, so Steam calls and store queries made
+// on its behalf are cancelled when the deadline passes or the client disconnects.
+// A zero timeout leaves the context untouched.
+func WithDeadline(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// maxClientHeader bounds the client-supplied strings kept with each change.
+const maxClientHeader = 256
+
+// WithClientInfo attaches the caller's address, User-Agent and X-Client
+// header to the request context, so the store records them with changes.
+func WithClientInfo(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		info := models.ClientInfo{
+			IP:        ip,
+			UserAgent: truncate(r.UserAgent(), maxClientHeader),
+			Client:    truncate(r.Header.Get("X-Client"), maxClientHeader),
+		}
+		next(w, r.WithContext(store.WithClientInfo(r.Context(), info)))
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+type contextKey int
+
+const (
+	steamIDKey contextKey = iota
+	sessionKey
+)
+
+// ValidateSteamID checks the {steamId} path segment (the first one after prefix)
+// and rejects the request with 400 if it is not a valid SteamID, before any
+// store or Steam access happens. Parsed IDs are stored in the request context
+// for pathSteamID. With allowVanity, vanity names and profile links are let
+// through for the handler to resolve.
+func ValidateSteamID(prefix string, allowVanity bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw := extractID(r, prefix)
+		if raw == "" {
+			http.Error(w, "Missing Steam ID", http.StatusBadRequest)
+			return
+		}
+
+		id, err := steamid.Parse(raw)
+		if err == nil {
+			next(w, r.WithContext(context.WithValue(r.Context(), steamIDKey, id)))
+			return
+		}
+		if allowVanity && (steamid.IsVanityName(raw) || service.IsProfileURL(raw)) {
+			next(w, r)
+			return
+		}
+		writeError(w, err)
+	}
+}
+
+// pathSteamID returns the SteamID parsed by ValidateSteamID, if any.
+func pathSteamID(r *http.Request) (steamid.ID, bool) {
+	id, ok := r.Context().Value(steamIDKey).(steamid.ID)
+	return id, ok
+}
+
+// RequireOwner only lets a request modify data under /{steamId}/ when it carries
+// a session token for that same SteamID. Reads pass through unauthenticated.
+// It must run inside ValidateSteamID.
+func RequireOwner(tokens *auth.TokenIssuer, next http.HandlerFunc) http.HandlerFunc {
+	owner := requireSession(tokens, "You can only change your own data", next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+		owner(w, r)
+	}
+}
+
+// RequirePrivate is RequireOwner for data only its owner may see: reads need
+// the owner's session as well. It must run inside ValidateSteamID.
+func RequirePrivate(tokens *auth.TokenIssuer, next http.HandlerFunc) http.HandlerFunc {
+	return requireSession(tokens, "You can only access your own data", next)
+}
+
+func requireSession(tokens *auth.TokenIssuer, forbidden string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, err := tokens.Verify(r.Context(), bearerToken(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		id, ok := pathSteamID(r)
+		if !ok || session.SteamID != id.String() {
+			writeJSONError(w, http.StatusForbidden, "forbidden", forbidden)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), sessionKey, session)))
+	}
+}
+
+// requestSession returns the session attached by RequireOwner, if any.
+func requestSession(r *http.Request) (*auth.Session, bool) {
+	s, ok := r.Context().Value(sessionKey).(*auth.Session)
+	return s, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// RequireAdmin only lets requests through that carry token as a Bearer token.
+// With an empty token the admin endpoints are disabled.
+func RequireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, "invalid_token", "Admin token required")
+			return
+		}
+		next(w, r)
+	}
+}
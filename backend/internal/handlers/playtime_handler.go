@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PlaytimeHandler struct {
+	playtime *service.PlaytimeService
+}
+
+func NewPlaytimeHandler(playtime *service.PlaytimeService) *PlaytimeHandler {
+	return &PlaytimeHandler{playtime: playtime}
+}
+
+// defaultTrendRange is how far back a trend goes when ?from= is not given.
+var defaultTrendRange = map[service.Granularity]time.Duration{
+	service.Daily:   30 * 24 * time.Hour,
+	service.Weekly:  12 * 7 * 24 * time.Hour,
+	service.Monthly: 365 * 24 * time.Hour,
+}
+
+// minBucketLength is the shortest span of each bucket size, used to cap how
+// many buckets one request can ask for.
+var minBucketLength = map[service.Granularity]time.Duration{
+	service.Daily:   24 * time.Hour,
+	service.Weekly:  7 * 24 * time.Hour,
+	service.Monthly: 28 * 24 * time.Hour,
+}
+
+const maxTrendBuckets = 1000
+
+// HandlePlaytime serves playtime history built from snapshots:
+//
+//	/api/data/{steamId}/playtime                 totals per ?bucket=day|week|month
+//	/api/data/{steamId}/playtime/games           per-game totals, most played first
+//	/api/data/{steamId}/playtime/games/{appId}   one game's totals per bucket
+//
+// ?from= and ?to= take RFC 3339 or Unix seconds.
+func (h *PlaytimeHandler) HandlePlaytime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/data/"), "/"), "/")
+	if len(parts) < 2 || parts[1] != "playtime" || len(parts) > 4 || (len(parts) > 2 && parts[2] != "games") {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	granularity := service.Daily
+	if v := q.Get("bucket"); v != "" {
+		g, err := service.ParseGranularity(v)
+		if err != nil {
+			http.Error(w, "Invalid bucket", http.StatusBadRequest)
+			return
+		}
+		granularity = g
+	}
+
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		t, err := parseSince(v)
+		if err != nil {
+			http.Error(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultTrendRange[granularity])
+	if v := q.Get("from"); v != "" {
+		t, err := parseSince(v)
+		if err != nil {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	if len(parts) != 3 && to.Sub(from)/minBucketLength[granularity] > maxTrendBuckets {
+		http.Error(w, "Range too large for bucket", http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	var err error
+	switch len(parts) {
+	case 2:
+		result, err = h.playtime.Trend(r.Context(), id.String(), 0, granularity, from, to)
+	case 3:
+		result, err = h.playtime.TopGames(r.Context(), id.String(), from, to)
+	case 4:
+		appID, convErr := strconv.Atoi(parts[3])
+		if convErr != nil || appID <= 0 {
+			http.Error(w, "Invalid App ID", http.StatusBadRequest)
+			return
+		}
+		result, err = h.playtime.Trend(r.Context(), id.String(), appID, granularity, from, to)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"backend/internal/models"
 	"backend/internal/service"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -18,21 +21,63 @@ func NewSteamHandler(client *service.SteamClient) *SteamHandler {
 
 // ExtractPathVar is a helper since we might not have gorilla/mux working yet.
 // Assumes path pattern like /api/steam/user/{id}
-func extractID(path string, prefix string) string {
-	return strings.TrimPrefix(path, prefix)
+func extractID(r *http.Request, prefix string) string {
+	return pathSegments(r, prefix)[0]
+}
+
+// pathSegments splits the path after prefix into unescaped segments. It works on
+// the escaped path so a URL-encoded profile link stays a single segment.
+func pathSegments(r *http.Request, prefix string) []string {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+	for i, p := range parts {
+		if v, err := url.PathUnescape(p); err == nil {
+			parts[i] = v
+		}
+	}
+	return parts
+}
+
+// resolveID turns a SteamID in any format, a profile link or a vanity name into
+// a SteamID64, writing the error response itself when that fails.
+func (h *SteamHandler) resolveID(w http.ResponseWriter, r *http.Request, raw string) (string, bool) {
+	if id, ok := pathSteamID(r); ok {
+		return id.String(), true
+	}
+	id, err := h.clientFor(r).ResolveSteamID(r.Context(), raw)
+	if err != nil {
+		writeError(w, err)
+		return "", false
+	}
+	return id, true
+}
+
+func (h *SteamHandler) clientFor(r *http.Request) *service.SteamClient {
+	return clientFor(r, h.client)
+}
+
+// clientFor honours Cache-Control: no-cache by skipping cached Steam responses.
+func clientFor(r *http.Request, client *service.SteamClient) *service.SteamClient {
+	if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") {
+		return client.WithoutCache()
+	}
+	return client
 }
 
 func (h *SteamHandler) GetUserSummary(w http.ResponseWriter, r *http.Request) {
 	// Pattern: /api/steam/user/
-	id := extractID(r.URL.Path, "/api/steam/user/")
+	id := extractID(r, "/api/steam/user/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	user, err := h.client.GetUserSummary(id)
+	user, err := h.clientFor(r).GetUserSummary(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	if user == nil {
@@ -46,17 +91,32 @@ func (h *SteamHandler) GetUserSummary(w http.ResponseWriter, r *http.Request) {
 
 func (h *SteamHandler) GetOwnedGames(w http.ResponseWriter, r *http.Request) {
 	// Pattern: /api/steam/games/
-	id := extractID(r.URL.Path, "/api/steam/games/")
+	id := extractID(r, "/api/steam/games/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	games, err := h.client.GetOwnedGames(id)
+	// Optional: ?sort=last_played|recent|playtime|deck|name
+	sortOrder := r.URL.Query().Get("sort")
+	less, ok := gameSorts[sortOrder]
+	if sortOrder != "" && !ok {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	games, err := h.clientFor(r).GetOwnedGames(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	if less != nil {
+		sort.SliceStable(games, func(i, j int) bool { return less(games[i], games[j]) })
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(games)
@@ -64,38 +124,116 @@ func (h *SteamHandler) GetOwnedGames(w http.ResponseWriter, r *http.Request) {
 
 func (h *SteamHandler) GetPlayerAchievements(w http.ResponseWriter, r *http.Request) {
 	// Pattern: /api/steam/achievements/{steamId}/{appId}
-	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/steam/achievements/"), "/")
+	parts := pathSegments(r, "/api/steam/achievements/")
 	if len(parts) < 2 {
 		http.Error(w, "Invalid path parameters", http.StatusBadRequest)
 		return
 	}
-	steamID := parts[0]
+	steamID, ok := h.resolveID(w, r, parts[0])
+	if !ok {
+		return
+	}
 	appID, err := strconv.Atoi(parts[1])
 	if err != nil {
 		http.Error(w, "Invalid App ID", http.StatusBadRequest)
 		return
 	}
 
-	achievements, err := h.client.GetPlayerAchievements(steamID, appID)
+	// Optional controls: ?l=<language>&revealHidden=true&sort=rarest|common&minPercent=&maxPercent=
+	query := r.URL.Query()
+	minPercent, maxPercent := 0.0, 100.0
+	for name, target := range map[string]*float64{"minPercent": &minPercent, "maxPercent": &maxPercent} {
+		if v := query.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || !(f >= 0 && f <= 100) {
+				http.Error(w, "Invalid "+name+", want a percentage from 0 to 100", http.StatusBadRequest)
+				return
+			}
+			*target = f
+		}
+	}
+	if minPercent > maxPercent {
+		http.Error(w, "minPercent is greater than maxPercent", http.StatusBadRequest)
+		return
+	}
+	sortOrder := query.Get("sort")
+	if sortOrder != "" && sortOrder != "rarest" && sortOrder != "common" {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	if lang := query.Get("l"); !validLanguage(lang) {
+		http.Error(w, "Invalid language", http.StatusBadRequest)
+		return
+	}
+	opts := service.AchievementOptions{
+		Language:     query.Get("l"),
+		RevealHidden: query.Get("revealHidden") == "true",
+	}
+
+	achievements, err := h.clientFor(r).GetPlayerAchievements(r.Context(), steamID, appID, opts)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	if query.Has("minPercent") || query.Has("maxPercent") {
+		achievements = filterByRarity(achievements, minPercent, maxPercent)
+	}
+	if sortOrder != "" {
+		sortByRarity(achievements, sortOrder == "rarest")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(achievements)
 }
 
+func (h *SteamHandler) GetPlayerStats(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/steam/stats/{steamId}/{appId}?l=<language>
+	parts := pathSegments(r, "/api/steam/stats/")
+	if len(parts) < 2 {
+		http.Error(w, "Invalid path parameters", http.StatusBadRequest)
+		return
+	}
+	steamID, ok := h.resolveID(w, r, parts[0])
+	if !ok {
+		return
+	}
+	appID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		http.Error(w, "Invalid App ID", http.StatusBadRequest)
+		return
+	}
+	lang := r.URL.Query().Get("l")
+	if !validLanguage(lang) {
+		http.Error(w, "Invalid language", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.clientFor(r).GetPlayerStats(r.Context(), steamID, appID, lang)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 func (h *SteamHandler) GetFriendList(w http.ResponseWriter, r *http.Request) {
-	id := extractID(r.URL.Path, "/api/steam/friends/")
+	id := extractID(r, "/api/steam/friends/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	friends, err := h.client.GetFriendList(id)
+	friends, err := h.clientFor(r).GetFriendList(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -103,15 +241,19 @@ func (h *SteamHandler) GetFriendList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SteamHandler) GetRecentlyPlayedGames(w http.ResponseWriter, r *http.Request) {
-	id := extractID(r.URL.Path, "/api/steam/recently-played/")
+	id := extractID(r, "/api/steam/recently-played/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	games, err := h.client.GetRecentlyPlayedGames(id)
+	games, err := h.clientFor(r).GetRecentlyPlayedGames(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -119,15 +261,19 @@ func (h *SteamHandler) GetRecentlyPlayedGames(w http.ResponseWriter, r *http.Req
 }
 
 func (h *SteamHandler) GetSteamLevel(w http.ResponseWriter, r *http.Request) {
-	id := extractID(r.URL.Path, "/api/steam/level/")
+	id := extractID(r, "/api/steam/level/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	level, err := h.client.GetSteamLevel(id)
+	level, err := h.clientFor(r).GetSteamLevel(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -135,15 +281,19 @@ func (h *SteamHandler) GetSteamLevel(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SteamHandler) GetPlayerBans(w http.ResponseWriter, r *http.Request) {
-	id := extractID(r.URL.Path, "/api/steam/bans/")
+	id := extractID(r, "/api/steam/bans/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	bans, err := h.client.GetPlayerBans(id)
+	bans, err := h.clientFor(r).GetPlayerBans(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -151,17 +301,80 @@ func (h *SteamHandler) GetPlayerBans(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SteamHandler) GetBadges(w http.ResponseWriter, r *http.Request) {
-	id := extractID(r.URL.Path, "/api/steam/badges/")
+	id := extractID(r, "/api/steam/badges/")
 	if id == "" {
 		http.Error(w, "Missing Steam ID", http.StatusBadRequest)
 		return
 	}
+	id, ok := h.resolveID(w, r, id)
+	if !ok {
+		return
+	}
 
-	badges, err := h.client.GetBadges(id)
+	badges, err := h.clientFor(r).GetBadges(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(badges)
 }
+
+// GetClientStats reports outbound Steam traffic counters (requests, retries, 429s, cache hits).
+func (h *SteamHandler) GetClientStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.client.Stats())
+}
+
+// gameSorts are the ?sort= orders supported by GetOwnedGames. All but "name"
+// put the largest value first.
+var gameSorts = map[string]func(a, b models.SteamGame) bool{
+	"last_played": func(a, b models.SteamGame) bool { return a.RTimeLastPlayed > b.RTimeLastPlayed },
+	"recent":      func(a, b models.SteamGame) bool { return a.Playtime2Weeks > b.Playtime2Weeks },
+	"playtime":    func(a, b models.SteamGame) bool { return a.PlaytimeForever > b.PlaytimeForever },
+	"deck":        func(a, b models.SteamGame) bool { return a.PlaytimeDeckForever > b.PlaytimeDeckForever },
+	"name":        func(a, b models.SteamGame) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+}
+
+// filterByRarity keeps achievements whose global unlock rate is within [min, max].
+// Achievements without rarity data are dropped.
+func filterByRarity(achievements []models.SteamAchievement, min, max float64) []models.SteamAchievement {
+	result := make([]models.SteamAchievement, 0, len(achievements))
+	for _, a := range achievements {
+		if a.GlobalPercent != nil && *a.GlobalPercent >= min && *a.GlobalPercent <= max {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// sortByRarity orders achievements by global unlock rate; those without rarity data go last.
+func sortByRarity(achievements []models.SteamAchievement, rarestFirst bool) {
+	sort.SliceStable(achievements, func(i, j int) bool {
+		a, b := achievements[i].GlobalPercent, achievements[j].GlobalPercent
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		if rarestFirst {
+			return *a < *b
+		}
+		return *a > *b
+	})
+}
+
+// validLanguage accepts Steam API language names such as "german" or "schinese".
+func validLanguage(lang string) bool {
+	if len(lang) > 32 {
+		return false
+	}
+	for _, c := range lang {
+		if (c < 'a' || c > 'z') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
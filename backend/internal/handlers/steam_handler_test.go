@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func percent(f float64) *float64 { return &f }
+
+func achievementNames(achievements []models.SteamAchievement) []string {
+	names := make([]string, len(achievements))
+	for i, a := range achievements {
+		names[i] = a.APIName
+	}
+	return names
+}
+
+func rarityFixture() []models.SteamAchievement {
+	return []models.SteamAchievement{
+		{APIName: "common", GlobalPercent: percent(80)},
+		{APIName: "unknown"},
+		{APIName: "rare", GlobalPercent: percent(2.5)},
+		{APIName: "tied_a", GlobalPercent: percent(20)},
+		{APIName: "tied_b", GlobalPercent: percent(20)},
+		{APIName: "edge", GlobalPercent: percent(50)},
+	}
+}
+
+func TestFilterByRarity(t *testing.T) {
+	tests := []struct {
+		min, max float64
+		want     []string
+	}{
+		{0, 100, []string{"common", "rare", "tied_a", "tied_b", "edge"}},
+		// Bounds are inclusive.
+		{20, 50, []string{"tied_a", "tied_b", "edge"}},
+		{0, 5, []string{"rare"}},
+		{90, 100, []string{}},
+	}
+	for _, tt := range tests {
+		got := achievementNames(filterByRarity(rarityFixture(), tt.min, tt.max))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterByRarity(%v, %v) = %v, want %v", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSortByRarity(t *testing.T) {
+	// Ties keep their original order and achievements without rarity go last.
+	tests := []struct {
+		rarestFirst bool
+		want        []string
+	}{
+		{true, []string{"rare", "tied_a", "tied_b", "edge", "common", "unknown"}},
+		{false, []string{"common", "edge", "tied_a", "tied_b", "rare", "unknown"}},
+	}
+	for _, tt := range tests {
+		achievements := rarityFixture()
+		sortByRarity(achievements, tt.rarestFirst)
+		if got := achievementNames(achievements); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortByRarity(rarestFirst=%v) = %v, want %v", tt.rarestFirst, got, tt.want)
+		}
+	}
+}
+
+func TestGetPlayerAchievementsRejectsInvalidPercentRange(t *testing.T) {
+	// Every case is rejected before Steam is called.
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected Steam call %s", r.URL.Path)
+	}))
+	defer steam.Close()
+	h := NewSteamHandler(service.NewSteamClient("key", service.WithBaseURL(steam.URL)))
+
+	for _, query := range []string{
+		"minPercent=80&maxPercent=20",
+		"minPercent=-1",
+		"maxPercent=100.5",
+		"minPercent=NaN",
+		"maxPercent=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/steam/achievements/76561197960287930/440?"+query, nil)
+		rec := httptest.NewRecorder()
+		h.GetPlayerAchievements(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type SyncHandler struct {
+	sync *service.AchievementSyncService
+	jobs *service.JobQueue
+}
+
+func NewSyncHandler(sync *service.AchievementSyncService, jobs *service.JobQueue) *SyncHandler {
+	return &SyncHandler{sync: sync, jobs: jobs}
+}
+
+// AchievementSummary is the library-wide completion built from stored progress.
+type AchievementSummary struct {
+	Games             int                          `json:"games"`
+	Unlocked          int                          `json:"unlocked"`
+	Total             int                          `json:"total"`
+	CompletionPercent float64                      `json:"completionPercent"`
+	Progress          []models.AchievementProgress `json:"progress"`
+}
+
+func (h *SyncHandler) HandleAchievementSync(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/sync/achievements/{steamId}
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		job, err := h.jobs.Enqueue(r.Context(), service.AchievementSyncJob, id.String(), nil)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+
+	case http.MethodGet:
+		progress, err := h.sync.Progress(r.Context(), id.String())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		summary := AchievementSummary{Progress: progress}
+		for _, p := range progress {
+			if p.Total == 0 {
+				continue
+			}
+			summary.Games++
+			summary.Unlocked += p.Unlocked
+			summary.Total += p.Total
+		}
+		if summary.Total > 0 {
+			summary.CompletionPercent = float64(summary.Unlocked) * 100 / float64(summary.Total)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summary)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
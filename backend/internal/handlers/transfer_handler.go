@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportSize bounds import uploads.
+const maxImportSize = 10 << 20
+
+type TransferHandler struct {
+	transfer *service.TransferService
+}
+
+func NewTransferHandler(transfer *service.TransferService) *TransferHandler {
+	return &TransferHandler{transfer: transfer}
+}
+
+// Export downloads the user's tracker data as ?format=json (the default) or csv.
+func (h *TransferHandler) Export(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/data/{steamId}/export
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.transfer.Export(r.Context(), id.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	disposition := fmt.Sprintf(`attachment; filename="steam-tracker-%s.%s"`, id, format)
+	if format == "csv" {
+		// Written in full before any of it is sent, so a failure is an error
+		// response rather than a truncated file.
+		var body bytes.Buffer
+		if err := service.WriteCSV(&body, export); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Disposition", disposition)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		body.WriteTo(w)
+		return
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
+// Import reads tracker data from the request body and returns a report.
+// ?format=json|csv defaults from the Content-Type; ?mode=merge (the default)
+// or replace; ?dryRun=true reports without saving.
+func (h *TransferHandler) Import(w http.ResponseWriter, r *http.Request) {
+	// Pattern: /api/data/{steamId}/import
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := pathSteamID(r)
+	if !ok {
+		http.Error(w, "Invalid Steam ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = "csv"
+		}
+	}
+	mode := models.ImportMode(query.Get("mode"))
+	if mode == "" {
+		mode = models.ImportMerge
+	}
+	if mode != models.ImportMerge && mode != models.ImportReplace {
+		http.Error(w, "Invalid mode", http.StatusBadRequest)
+		return
+	}
+	var dryRun bool
+	if v := query.Get("dryRun"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dryRun", http.StatusBadRequest)
+			return
+		}
+		dryRun = b
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var rows []service.ImportRow
+	var err error
+	switch format {
+	case "json":
+		rows, err = service.ParseJSONImport(body)
+	case "csv":
+		rows, err = service.ParseCSVImport(body)
+	default:
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, "Import too large", http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, service.ErrInvalidImport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		writeError(w, err)
+		return
+	}
+
+	report, err := h.transfer.Import(r.Context(), id.String(), rows, mode, dryRun)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// GameMetadata is storefront information about an app, kept in the local catalog.
+type GameMetadata struct {
+	AppID            int      `json:"appid"`
+	Name             string   `json:"name"`
+	Type             string   `json:"type"`
+	ShortDescription string   `json:"shortDescription"`
+	HeaderImage      string   `json:"headerImage"`
+	Developers       []string `json:"developers"`
+	Publishers       []string `json:"publishers"`
+	Genres           []string `json:"genres"`
+	Categories       []string `json:"categories"`
+	ReleaseDate      string   `json:"releaseDate"` // As displayed by the store, e.g. "10 Oct, 2007"
+	ComingSoon       bool     `json:"comingSoon"`
+	IsFree           bool     `json:"isFree"`
+	// Unavailable is set when the store has no page for the app.
+	Unavailable bool      `json:"unavailable"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+}
+
+// LibraryGame is an owned game enriched with catalog metadata, if known yet.
+type LibraryGame struct {
+	SteamGame
+	Metadata *GameMetadata `json:"metadata"`
+}
+
+// API Response Wrappers
+
+// AppDetailsResponse is keyed by the requested app ID as a string.
+type AppDetailsResponse map[string]struct {
+	Success bool       `json:"success"`
+	Data    AppDetails `json:"data"`
+}
+
+type AppDetails struct {
+	Type             string   `json:"type"`
+	Name             string   `json:"name"`
+	SteamAppID       int      `json:"steam_appid"`
+	IsFree           bool     `json:"is_free"`
+	ShortDescription string   `json:"short_description"`
+	HeaderImage      string   `json:"header_image"`
+	Developers       []string `json:"developers"`
+	Publishers       []string `json:"publishers"`
+	Genres           []struct {
+		ID          string `json:"id"`
+		Description string `json:"description"`
+	} `json:"genres"`
+	Categories []struct {
+		ID          int    `json:"id"`
+		Description string `json:"description"`
+	} `json:"categories"`
+	ReleaseDate struct {
+		ComingSoon bool   `json:"coming_soon"`
+		Date       string `json:"date"`
+	} `json:"release_date"`
+}
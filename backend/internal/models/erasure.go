@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ErasureReceipt confirms that everything held about a user was deleted.
+type ErasureReceipt struct {
+	SteamID  string    `json:"steamId"`
+	ErasedAt time.Time `json:"erasedAt"`
+	// Deleted is the number of rows removed from each table.
+	Deleted map[string]int64 `json:"deleted"`
+}
+
+// SignedErasureReceipt is a receipt plus a token that proves the server
+// issued it. The token is base64url(receipt JSON) "." base64url(signature).
+type SignedErasureReceipt struct {
+	Receipt ErasureReceipt `json:"receipt"`
+	Token   string         `json:"token"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ClientInfo identifies where a change to user data came from.
+type ClientInfo struct {
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	// Client is the app's self-reported name and version, from the X-Client header.
+	Client string `json:"client,omitempty"`
+}
+
+// GameDataRevision is one change to a game's local data. Previous is nil
+// when the data was first saved; Data is nil when it was deleted.
+type GameDataRevision struct {
+	Revision  int64          `json:"revision"`
+	AppID     int            `json:"appId"`
+	Previous  *LocalGameData `json:"previous"`
+	Data      *LocalGameData `json:"data"`
+	ChangedAt time.Time      `json:"changedAt"`
+	Client    ClientInfo     `json:"client"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	// JobQueued jobs wait for RunAt; failed attempts that will be retried go back to queued.
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	// JobDead jobs failed permanently or ran out of attempts.
+	JobDead JobStatus = "dead"
+)
+
+// Job is a unit of long-running background work in the persistent queue.
+// Payload, Progress and Result are owned by the job type.
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	SteamID     string          `json:"steamId"`
+	Status      JobStatus       `json:"status"`
+	Payload     json.RawMessage `json:"-"`
+	Progress    json.RawMessage `json:"progress,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	RunAt       time.Time       `json:"runAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+
+	// LeaseID identifies the worker holding a running job; LeasedUntil is
+	// when another worker may take it over.
+	LeaseID     string    `json:"-"`
+	LeasedUntil time.Time `json:"-"`
+}
+
+// Finished reports whether the job will not run again.
+func (j *Job) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobDead
+}
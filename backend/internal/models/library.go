@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// LibraryEntry is the last known state of an owned game, as persisted by
+// library refreshes.
+type LibraryEntry struct {
+	AppID           int       `json:"appId"`
+	Name            string    `json:"name"`
+	PlaytimeForever int       `json:"playtimeForever"`
+	FirstSeen       time.Time `json:"firstSeen"`
+	LastSeen        time.Time `json:"lastSeen"`
+	// Removed is set once the game disappears from the library (refund,
+	// revoked license, ...). It is cleared if the game comes back.
+	Removed bool `json:"removed"`
+}
+
+type LibraryEventType string
+
+const (
+	LibraryEventAcquired    LibraryEventType = "acquired"
+	LibraryEventRemoved     LibraryEventType = "removed"
+	LibraryEventFirstPlayed LibraryEventType = "first_played"
+)
+
+// LibraryEvent is a change detected between two library refreshes.
+type LibraryEvent struct {
+	ID    int64            `json:"id"`
+	AppID int              `json:"appId"`
+	Name  string           `json:"name"`
+	Type  LibraryEventType `json:"type"`
+	At    time.Time        `json:"at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// PlaytimeDelta is the playtime, in minutes, recorded for one game on one day
+// (UTC). After compaction, older deltas are kept per month instead, dated on
+// the first of the month.
+type PlaytimeDelta struct {
+	AppID   int       `json:"appId"`
+	Day     time.Time `json:"day"`
+	Minutes int       `json:"minutes"`
+}
+
+// PlaytimeBucket is the total playtime within one day, week or month.
+type PlaytimeBucket struct {
+	Start   time.Time `json:"start"`
+	Minutes int       `json:"minutes"`
+}
+
+// PlaytimeTotal is a game's total playtime within a period.
+type PlaytimeTotal struct {
+	AppID   int `json:"appId"`
+	Minutes int `json:"minutes"`
+}
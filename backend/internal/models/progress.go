@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AchievementProgress is a user's achievement completion for one game.
+type AchievementProgress struct {
+	AppID    int `json:"appId"`
+	Unlocked int `json:"unlocked"`
+	Total    int `json:"total"`
+	// LastUnlock is the Unix time of the most recent unlock, 0 if none.
+	LastUnlock int64     `json:"lastUnlock"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// SyncProgress reports how far a library-wide achievement sync has got.
+type SyncProgress struct {
+	// Total is the number of games with stats; Skipped counts games without any.
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
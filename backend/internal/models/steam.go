@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // SteamUser represents a user's Steam profile summary.
 type SteamUser struct {
 	SteamID      string `json:"steamid"`
@@ -10,14 +12,34 @@ type SteamUser struct {
 	AvatarFull   string `json:"avatarfull"`
 	LastLogoff   int    `json:"lastlogoff"`
 	PersonState  int    `json:"personastate"`
+	// CommunityVisibilityState is 3 for public profiles; anything else hides
+	// games and stats from the Web API.
+	CommunityVisibilityState int `json:"communityvisibilitystate"`
+}
+
+// SteamFriend is a friend's profile summary plus the friendship details.
+type SteamFriend struct {
+	SteamUser
+	Relationship string `json:"relationship"`
+	FriendSince  int    `json:"friend_since"`
 }
 
 // SteamGame represents a game owned by a user.
+// Playtimes are in minutes; RTimeLastPlayed is a Unix timestamp (0 if never played).
 type SteamGame struct {
-	AppID           int    `json:"appid"`
-	Name            string `json:"name"`
-	PlaytimeForever int    `json:"playtime_forever"`
-	ImgIconURL      string `json:"img_icon_url"`
+	AppID                  int    `json:"appid"`
+	Name                   string `json:"name"`
+	PlaytimeForever        int    `json:"playtime_forever"`
+	ImgIconURL             string `json:"img_icon_url"`
+	Playtime2Weeks         int    `json:"playtime_2weeks"`
+	RTimeLastPlayed        int64  `json:"rtime_last_played"`
+	PlaytimeWindowsForever int    `json:"playtime_windows_forever"`
+	PlaytimeMacForever     int    `json:"playtime_mac_forever"`
+	PlaytimeLinuxForever   int    `json:"playtime_linux_forever"`
+	PlaytimeDeckForever    int    `json:"playtime_deck_forever"`
+	PlaytimeDisconnected   int    `json:"playtime_disconnected"`
+	// HasCommunityVisibleStats is false for games without stats or achievements.
+	HasCommunityVisibleStats bool `json:"has_community_visible_stats"`
 }
 
 // SteamAchievement represents an achievement for a game.
@@ -25,8 +47,21 @@ type SteamAchievement struct {
 	APIName     string `json:"apiname"`
 	Achieved    bool   `json:"achieved"` // Mapped from int 0/1 in logic, but API returns int usually, we'll handle at service level or struct scan
 	UnlockTime  int    `json:"unlocktime"`
-	Name        string `json:"name"`        // From Schema
-	Description string `json:"description"` // From Schema
+	Name        string `json:"name"`        // From Schema; empty for locked hidden achievements unless revealed
+	Description string `json:"description"` // From Schema; empty for locked hidden achievements unless revealed
+	Icon        string `json:"icon"`        // From Schema, shown once unlocked
+	IconGray    string `json:"icongray"`    // From Schema, shown while locked
+	Hidden      bool   `json:"hidden"`      // From Schema
+	// GlobalPercent is the share of all players who unlocked it, 0-100. Lower is rarer.
+	// Nil when Steam has no rarity data for the game.
+	GlobalPercent *float64 `json:"globalPercent,omitempty"`
+}
+
+// SteamStat is a numeric per-game stat (kills, distance, wins...) for a player.
+type SteamStat struct {
+	APIName     string  `json:"apiname"`
+	DisplayName string  `json:"displayName"` // From Schema; falls back to APIName
+	Value       float64 `json:"value"`
 }
 
 // API Response Wrappers
@@ -48,6 +83,8 @@ type PlayerAchievementsResponse struct {
 	PlayerStats struct {
 		SteamID      string `json:"steamID"`
 		GameName     string `json:"gameName"`
+		Success      bool   `json:"success"`
+		Error        string `json:"error"`
 		Achievements []struct {
 			APIName    string `json:"apiname"`
 			Achieved   int    `json:"achieved"`
@@ -56,6 +93,17 @@ type PlayerAchievementsResponse struct {
 	} `json:"playerstats"`
 }
 
+type UserStatsForGameResponse struct {
+	PlayerStats struct {
+		SteamID  string `json:"steamID"`
+		GameName string `json:"gameName"`
+		Stats    []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+		} `json:"stats"`
+	} `json:"playerstats"`
+}
+
 type GameSchemaResponse struct {
 	Game struct {
 		AvailableGameStats struct {
@@ -63,13 +111,29 @@ type GameSchemaResponse struct {
 				Name        string `json:"name"`
 				DisplayName string `json:"displayName"`
 				Description string `json:"description"`
+				Hidden      int    `json:"hidden"`
 				Icon        string `json:"icon"`
 				IconGray    string `json:"icongray"`
 			} `json:"achievements"`
+			Stats []struct {
+				Name         string  `json:"name"`
+				DefaultValue float64 `json:"defaultvalue"`
+				DisplayName  string  `json:"displayName"`
+			} `json:"stats"`
 		} `json:"availableGameStats"`
 	} `json:"game"`
 }
 
+type GlobalAchievementPercentagesResponse struct {
+	AchievementPercentages struct {
+		Achievements []struct {
+			Name string `json:"name"`
+			// Steam has sent this both as a number and as a quoted string.
+			Percent json.Number `json:"percent"`
+		} `json:"achievements"`
+	} `json:"achievementpercentages"`
+}
+
 type FriendListResponse struct {
 	FriendsList struct {
 		Friends []struct {
@@ -86,3 +150,11 @@ type RecentlyPlayedGamesResponse struct {
 		Games      []SteamGame `json:"games"`
 	} `json:"response"`
 }
+
+type ResolveVanityURLResponse struct {
+	Response struct {
+		SteamID string `json:"steamid"`
+		Success int    `json:"success"`
+		Message string `json:"message"`
+	} `json:"response"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// ExportedGame is one game's tracker data in an export, with its name from
+// the user's library. Status is a name, as accepted by ParseLocalGameStatus.
+type ExportedGame struct {
+	AppID      int       `json:"appId"`
+	Name       string    `json:"name,omitempty"`
+	Status     string    `json:"status"`
+	Rating     *float64  `json:"rating,omitempty"`
+	IsFavorite bool      `json:"isFavorite"`
+	PlayOrder  *int      `json:"playOrder,omitempty"`
+	Notes      *string   `json:"notes,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Export is a user's tracker data as exported, and as imported from JSON.
+type Export struct {
+	SteamID    string         `json:"steamId"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Games      []ExportedGame `json:"games"`
+}
+
+type ImportMode string
+
+const (
+	// ImportMerge saves the imported games and keeps all others.
+	ImportMerge ImportMode = "merge"
+	// ImportReplace also deletes the games the import doesn't list.
+	ImportReplace ImportMode = "replace"
+)
+
+// ImportRowError is why one row of an import was skipped. Row counts the
+// file's games from 1, not counting a CSV header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	AppID   int    `json:"appId,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport says what an import changed or, on a dry run, would change.
+type ImportReport struct {
+	Mode      ImportMode       `json:"mode"`
+	DryRun    bool             `json:"dryRun"`
+	Rows      int              `json:"rows"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Deleted   int              `json:"deleted"`
+	Errors    []ImportRowError `json:"errors"`
+}
@@ -1,6 +1,12 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type LocalGameStatus int
 
@@ -12,6 +18,30 @@ const (
 	StatusDropped
 )
 
+var localGameStatusNames = []string{"none", "backlog", "playing", "completed", "dropped"}
+
+// String returns the status's name, as accepted by ParseLocalGameStatus.
+func (s LocalGameStatus) String() string {
+	if s >= 0 && int(s) < len(localGameStatusNames) {
+		return localGameStatusNames[s]
+	}
+	return strconv.Itoa(int(s))
+}
+
+// ParseLocalGameStatus accepts a status name, in any case, or its number.
+func ParseLocalGameStatus(v string) (LocalGameStatus, error) {
+	for i, name := range localGameStatusNames {
+		if strings.EqualFold(v, name) {
+			return LocalGameStatus(i), nil
+		}
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < int(StatusNone) || n > int(StatusDropped) {
+		return 0, fmt.Errorf("unknown status %q", v)
+	}
+	return LocalGameStatus(n), nil
+}
+
 // LocalGameData represents the user's custom data for a game.
 type LocalGameData struct {
 	AppID      int             `json:"appId"`
@@ -20,6 +50,22 @@ type LocalGameData struct {
 	Status     LocalGameStatus `json:"status"`
 	IsFavorite bool            `json:"isFavorite"`
 	PlayOrder  *int            `json:"playOrder,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+// SameValues reports whether l and o hold the same user-editable values,
+// ignoring the app ID and timestamps.
+func (l *LocalGameData) SameValues(o *LocalGameData) bool {
+	return l.Status == o.Status && l.IsFavorite == o.IsFavorite &&
+		equalPtr(l.Rating, o.Rating) && equalPtr(l.Notes, o.Notes) && equalPtr(l.PlayOrder, o.PlayOrder)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func (l *LocalGameData) ToJSONString() (string, error) {
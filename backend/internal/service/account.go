@@ -0,0 +1,62 @@
+package service
+
+import (
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"time"
+)
+
+// AccountService erases users on request.
+type AccountService struct {
+	store  store.ErasureStore
+	cache  *ResponseCache
+	signer *auth.ReceiptSigner
+	now    func() time.Time
+}
+
+// NewAccountService creates the service. cache is the Steam client's
+// response cache, or nil if it has none.
+func NewAccountService(store store.ErasureStore, cache *ResponseCache, signer *auth.ReceiptSigner) *AccountService {
+	return &AccountService{store: store, cache: cache, signer: signer, now: time.Now}
+}
+
+// Erase deletes everything held about steamID, including the history of
+// their changes, their queued and running jobs and the Steam responses
+// cached for them or resolving their vanity names, and returns a signed
+// receipt listing what was removed. Sessions issued before the erasure stop
+// being accepted; signing in again registers the user afresh.
+func (a *AccountService) Erase(ctx context.Context, steamID string) (*models.SignedErasureReceipt, error) {
+	cached := userCacheFilter(steamID)
+	deleted, err := a.store.EraseUser(ctx, steamID, cached)
+	if err != nil {
+		return nil, err
+	}
+	// The store dropped the persistent cache tier in the same transaction.
+	// Clearing memory afterwards means it can't be refilled from there;
+	// other instances stop serving their copies once they revalidate.
+	if a.cache != nil {
+		a.cache.ForgetMemory(cached.Match)
+	}
+
+	receipt := models.ErasureReceipt{
+		SteamID:  steamID,
+		ErasedAt: a.now().UTC().Truncate(time.Second),
+		Deleted:  deleted,
+	}
+	token, err := a.signer.Sign(receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SignedErasureReceipt{Receipt: receipt, Token: token}, nil
+}
+
+// VerifyReceipt checks that token was issued by Erase and returns its receipt.
+func (a *AccountService) VerifyReceipt(token string) (*models.ErasureReceipt, error) {
+	var receipt models.ErasureReceipt
+	if err := a.signer.Verify(token, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/steamid"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccountServiceEraseReceipt(t *testing.T) {
+	ctx := context.Background()
+	const steamID = "76561197960287930"
+	memory := store.NewMemoryStore()
+	if err := memory.SaveUser(ctx, &models.SteamUser{SteamID: steamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	accounts := NewAccountService(memory, nil, auth.NewReceiptSigner([]byte("secret")))
+
+	signed, err := accounts.Erase(ctx, steamID)
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if signed.Receipt.SteamID != steamID || signed.Receipt.Deleted["users"] != 1 {
+		t.Errorf("receipt = %+v, want one users row deleted for %s", signed.Receipt, steamID)
+	}
+
+	receipt, err := accounts.VerifyReceipt(signed.Token)
+	if err != nil {
+		t.Fatalf("VerifyReceipt: %v", err)
+	}
+	if receipt.SteamID != steamID || !receipt.ErasedAt.Equal(signed.Receipt.ErasedAt) {
+		t.Errorf("verified receipt = %+v, want %+v", receipt, signed.Receipt)
+	}
+
+	payload, sig, _ := strings.Cut(signed.Token, ".")
+	for name, token := range map[string]string{
+		"altered payload": payload[:len(payload)-2] + "AA." + sig,
+		"other secret":    mustSign(t, auth.NewReceiptSigner([]byte("other")), signed.Receipt),
+		"session token":   mustIssue(t, steamID),
+	} {
+		if _, err := accounts.VerifyReceipt(token); !errors.Is(err, auth.ErrInvalidReceipt) {
+			t.Errorf("%s: VerifyReceipt = %v, want ErrInvalidReceipt", name, err)
+		}
+	}
+}
+
+func mustSign(t *testing.T, signer *auth.ReceiptSigner, v interface{}) string {
+	t.Helper()
+	token, err := signer.Sign(v)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+// mustIssue returns a session token signed with the same secret.
+func mustIssue(t *testing.T, steamID string) string {
+	t.Helper()
+	id, err := steamid.Parse(steamID)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	token, _, err := auth.NewTokenIssuer([]byte("secret"), time.Hour).Issue(id)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
+
+func TestAccountServiceEraseFencesJobsAndCache(t *testing.T) {
+	ctx := context.Background()
+	const steamID, otherID = "76561197960287930", "76561197960287931"
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer db.Close()
+
+	// Keys as SteamClient builds them, stored in both tiers.
+	erased := map[string]string{
+		"/ISteamUser/GetPlayerSummaries/v0002/?key=k&steamids=" + steamID:                   "{}",
+		"/ISteamUser/GetPlayerSummaries/v0002/?key=k&steamids=" + otherID + "%2C" + steamID: "{}",
+		"/IPlayerService/GetOwnedGames/v0001/?include_appinfo=1&key=k&steamid=" + steamID:   "{}",
+		"/ISteamUser/ResolveVanityURL/v0001/?key=k&vanityurl=someone":                       `{"response":{"steamid":"` + steamID + `","success":1}}`,
+	}
+	kept := map[string]string{
+		"/ISteamUser/GetPlayerSummaries/v0002/?key=k&steamids=" + otherID: "{}",
+		// Contains the erased SteamID, but is about someone else.
+		"/IPlayerService/GetOwnedGames/v0001/?key=k&steamid=" + steamID + "1": "{}",
+		"/ISteamUser/ResolveVanityURL/v0001/?key=k&vanityurl=other":           `{"response":{"steamid":"` + otherID + `","success":1}}`,
+		"/ISteamUserStats/GetSchemaForGame/v2/?appid=440&key=k":               `{"game":{"gameName":"` + steamID + `"}}`,
+	}
+	cache := NewResponseCache(10, db)
+	for key, value := range erased {
+		cache.Set(ctx, key, []byte(value), time.Hour)
+	}
+	for key, value := range kept {
+		cache.Set(ctx, key, []byte(value), time.Hour)
+	}
+	accounts := NewAccountService(db, cache, auth.NewReceiptSigner([]byte("secret")))
+
+	// A sync job for the user is running when the erasure comes in.
+	now := time.Now()
+	job := &models.Job{ID: "job1", Type: AchievementSyncJob, SteamID: steamID, Status: models.JobQueued, MaxAttempts: 3, RunAt: now, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if leased, err := db.LeaseJob(ctx, now, "lease1", now.Add(time.Minute)); err != nil || leased == nil {
+		t.Fatalf("LeaseJob = %v, %v", leased, err)
+	}
+	jobCtx := store.WithJobLease(ctx, "job1", "lease1")
+	if err := db.SaveAchievementProgress(jobCtx, steamID, &models.AchievementProgress{AppID: 440, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveAchievementProgress while leased: %v", err)
+	}
+
+	signed, err := accounts.Erase(ctx, steamID)
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	if signed.Receipt.Deleted["jobs"] != 1 || signed.Receipt.Deleted["achievement_progress"] != 1 {
+		t.Errorf("receipt deleted %v, want the job and its progress", signed.Receipt.Deleted)
+	}
+
+	// The job's next write is refused rather than re-creating data.
+	err = db.SaveAchievementProgress(jobCtx, steamID, &models.AchievementProgress{AppID: 570, UpdatedAt: now})
+	if !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("SaveAchievementProgress after erasure error = %v, want ErrLeaseLost", err)
+	}
+	if progress, err := db.GetAchievementProgress(ctx, steamID); err != nil || len(progress) != 0 {
+		t.Errorf("progress after erasure = %v, %v; want none", progress, err)
+	}
+
+	// A fresh cache reads only the persistent tier.
+	for name, c := range map[string]*ResponseCache{"memory": cache, "persistent": NewResponseCache(10, db)} {
+		for key := range erased {
+			if _, ok := c.Get(ctx, key); ok {
+				t.Errorf("%s tier still holds %s", name, key)
+			}
+		}
+		for key := range kept {
+			if _, ok := c.Get(ctx, key); !ok {
+				t.Errorf("%s tier dropped %s", name, key)
+			}
+		}
+	}
+	if signed.Receipt.Deleted["steam_cache"] != int64(len(erased)) {
+		t.Errorf("receipt deleted %d cache entries, want %d", signed.Receipt.Deleted["steam_cache"], len(erased))
+	}
+
+	if erasedAt, err := db.ErasedAt(ctx, steamID); err != nil || erasedAt.IsZero() {
+		t.Errorf("ErasedAt = %v, %v; want the erasure time", erasedAt, err)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// AchievementSyncJob is the job type of a library-wide achievement sync.
+const AchievementSyncJob = "achievement_sync"
+
+// AchievementSyncService walks a user's whole library and records achievement
+// completion per game, so clients don't have to call the achievements endpoint
+// once per game. Syncs run as AchievementSyncJob jobs on the job queue.
+type AchievementSyncService struct {
+	store       store.ProgressStore
+	steamClient *SteamClient
+	// Workers bounds how many games are fetched concurrently per sync.
+	Workers int
+	now     func() time.Time
+}
+
+func NewAchievementSyncService(store store.ProgressStore, steamClient *SteamClient) *AchievementSyncService {
+	return &AchievementSyncService{
+		store:       store,
+		steamClient: steamClient,
+		Workers:     4,
+		now:         time.Now,
+	}
+}
+
+// Progress returns the stored per-game results of past syncs.
+func (s *AchievementSyncService) Progress(ctx context.Context, steamID string) ([]models.AchievementProgress, error) {
+	return s.store.GetAchievementProgress(ctx, steamID)
+}
+
+// RunJob is the JobFunc for AchievementSyncJob. Its progress is a
+// models.SyncProgress. Private profiles and rejected keys are not retried.
+func (s *AchievementSyncService) RunJob(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+	progress, err := s.Sync(ctx, job.SteamID, func(p models.SyncProgress) { report(p) })
+	report(progress)
+	if errors.Is(err, ErrPrivateProfile) || errors.Is(err, ErrUnauthorizedKey) {
+		return nil, Permanent(err)
+	}
+	return nil, err
+}
+
+// Sync records achievement progress for every game in the user's library
+// that has stats. onProgress is called with the counts after each game.
+func (s *AchievementSyncService) Sync(ctx context.Context, steamID string, onProgress func(models.SyncProgress)) (models.SyncProgress, error) {
+	var progress models.SyncProgress
+	games, err := s.steamClient.GetOwnedGames(ctx, steamID)
+	if err != nil {
+		return progress, err
+	}
+
+	var appIDs []int
+	for _, g := range games {
+		if g.HasCommunityVisibleStats {
+			appIDs = append(appIDs, g.AppID)
+		} else {
+			progress.Skipped++
+		}
+	}
+	progress.Total = len(appIDs)
+	onProgress(progress)
+
+	var mu sync.Mutex
+	update := func(fn func(*models.SyncProgress)) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(&progress)
+		onProgress(progress)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A private profile or a rejected key fails every game the same way, so
+	// the first such error stops the sync.
+	var fatalOnce sync.Once
+	var fatal error
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range max(s.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for appID := range work {
+				if err := s.syncGame(ctx, steamID, appID, update); err != nil {
+					fatalOnce.Do(func() { fatal = err })
+					cancel()
+				}
+			}
+		}()
+	}
+feed:
+	for _, appID := range appIDs {
+		select {
+		case work <- appID:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fatal == nil {
+		// Cancelled from outside, e.g. on shutdown.
+		fatal = ctx.Err()
+	}
+	return progress, fatal
+}
+
+// syncGame records progress for one game. It only returns errors that make
+// the rest of the sync pointless, including losing the job's lease.
+func (s *AchievementSyncService) syncGame(ctx context.Context, steamID string, appID int, update func(func(*models.SyncProgress))) error {
+	states, err := s.steamClient.getAchievementStates(ctx, steamID, appID, "")
+	if errors.Is(err, ErrNoStats) {
+		update(func(p *models.SyncProgress) { p.Total--; p.Skipped++ })
+		return nil
+	}
+	if errors.Is(err, ErrPrivateProfile) || errors.Is(err, ErrUnauthorizedKey) {
+		return err
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("achievement sync for %s: app %d: %v", steamID, appID, err)
+			update(func(p *models.SyncProgress) { p.Failed++ })
+		}
+		return nil
+	}
+
+	progress := &models.AchievementProgress{AppID: appID, UpdatedAt: s.now()}
+	for _, a := range states.PlayerStats.Achievements {
+		progress.Total++
+		if a.Achieved == 1 {
+			progress.Unlocked++
+			progress.LastUnlock = max(progress.LastUnlock, int64(a.UnlockTime))
+		}
+	}
+	if err := s.store.SaveAchievementProgress(ctx, steamID, progress); err != nil {
+		if errors.Is(err, store.ErrLeaseLost) {
+			return err
+		}
+		log.Printf("achievement sync for %s: saving app %d: %v", steamID, appID, err)
+		update(func(p *models.SyncProgress) { p.Failed++ })
+		return nil
+	}
+	update(func(p *models.SyncProgress) { p.Done++ })
+	return nil
+}
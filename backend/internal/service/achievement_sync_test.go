@@ -0,0 +1,150 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const syncSteamID = "76561197960287930"
+
+// syncSteam serves a library of four games: 10 has achievements, 20 has no
+// stats flag, 30 has no stats on Steam's side and 40 fails upstream. owned and
+// achievements, if set, replace the responses for every game.
+type syncSteam struct {
+	owned        func(w http.ResponseWriter)
+	achievements func(w http.ResponseWriter)
+	calls        atomic.Int32
+}
+
+func (s *syncSteam) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.Contains(r.URL.Path, "GetOwnedGames"):
+		if s.owned != nil {
+			s.owned(w)
+			return
+		}
+		w.Write([]byte(`{"response": {"games": [
+			{"appid": 10, "has_community_visible_stats": true},
+			{"appid": 20},
+			{"appid": 30, "has_community_visible_stats": true},
+			{"appid": 40, "has_community_visible_stats": true}]}}`))
+	case strings.Contains(r.URL.Path, "GetPlayerAchievements"):
+		s.calls.Add(1)
+		if s.achievements != nil {
+			s.achievements(w)
+			return
+		}
+		switch r.URL.Query().Get("appid") {
+		case "10":
+			fmt.Fprintf(w, `{"playerstats": {"steamID": %q, "success": true, "achievements": [
+				{"apiname": "A", "achieved": 1, "unlocktime": 100},
+				{"apiname": "B", "achieved": 1, "unlocktime": 300},
+				{"apiname": "C", "achieved": 0, "unlocktime": 0}]}}`, syncSteamID)
+		case "30":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"playerstats": {"error": "Requested app has no stats", "success": false}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestSync(t *testing.T, steam *syncSteam) (*AchievementSyncService, *store.MemoryStore) {
+	t.Helper()
+	server := httptest.NewServer(steam)
+	t.Cleanup(server.Close)
+	db := store.NewMemoryStore()
+	client := NewSteamClient("key", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{}))
+	return NewAchievementSyncService(db, client), db
+}
+
+func TestAchievementSyncCountsGames(t *testing.T) {
+	ctx := context.Background()
+	s, db := newTestSync(t, &syncSteam{})
+
+	var reports int
+	progress, err := s.Sync(ctx, syncSteamID, func(models.SyncProgress) { reports++ })
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	// 20 is skipped up front, 30 once Steam reports it has no stats.
+	want := models.SyncProgress{Total: 2, Done: 1, Skipped: 2, Failed: 1}
+	if progress != want {
+		t.Errorf("progress = %+v, want %+v", progress, want)
+	}
+	if reports != 4 {
+		t.Errorf("onProgress called %d times, want once up front and once per game", reports)
+	}
+
+	saved, err := db.GetAchievementProgress(ctx, syncSteamID)
+	if err != nil {
+		t.Fatalf("GetAchievementProgress: %v", err)
+	}
+	if len(saved) != 1 || saved[0].AppID != 10 || saved[0].Unlocked != 2 || saved[0].Total != 3 || saved[0].LastUnlock != 300 {
+		t.Errorf("saved progress = %+v, want app 10 at 2 of 3, last unlock 300", saved)
+	}
+}
+
+func TestAchievementSyncFatalErrorsArePermanent(t *testing.T) {
+	tests := []struct {
+		name  string
+		steam *syncSteam
+		want  error
+	}{
+		{"private profile", &syncSteam{achievements: func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"playerstats": {"error": "Profile is not public", "success": false}}`))
+		}}, ErrPrivateProfile},
+		{"bad key", &syncSteam{owned: func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}}, ErrUnauthorizedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestSync(t, tt.steam)
+			s.Workers = 1
+
+			_, err := s.RunJob(context.Background(), &models.Job{SteamID: syncSteamID}, func(interface{}) {})
+			var permanent permanentError
+			if !errors.Is(err, tt.want) || !errors.As(err, &permanent) {
+				t.Fatalf("RunJob error = %v, want a permanent %v", err, tt.want)
+			}
+			// The first private result stops the sync rather than trying every game.
+			if n := tt.steam.calls.Load(); n > 1 {
+				t.Errorf("sync fetched achievements %d times after a fatal error", n)
+			}
+			if saved, _ := db.GetAchievementProgress(context.Background(), syncSteamID); len(saved) != 0 {
+				t.Errorf("saved progress %+v after a fatal error", saved)
+			}
+		})
+	}
+}
+
+func TestAchievementSyncStopsOnLostLease(t *testing.T) {
+	s, db := newTestSync(t, &syncSteam{})
+	s.Workers = 1
+	// No job holds this lease, as after the user's erasure.
+	ctx := store.WithJobLease(context.Background(), "gone", "lease")
+
+	_, err := s.RunJob(ctx, &models.Job{ID: "gone", SteamID: syncSteamID}, func(interface{}) {})
+	if !errors.Is(err, store.ErrLeaseLost) {
+		t.Fatalf("RunJob error = %v, want ErrLeaseLost", err)
+	}
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		t.Error("a lost lease was marked permanent")
+	}
+	if saved, _ := db.GetAchievementProgress(context.Background(), syncSteamID); len(saved) != 0 {
+		t.Errorf("saved progress %+v without the lease", saved)
+	}
+}
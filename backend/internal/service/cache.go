@@ -0,0 +1,261 @@
+package service
+
+import (
+	"backend/internal/store"
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTLs maps Steam Web API paths to how long their responses stay fresh.
+// Paths without an entry are not cached.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"/ISteamUserStats/GetSchemaForGame/v2/":                         6 * time.Hour,
+	"/IPlayerService/GetOwnedGames/v0001/":                          10 * time.Minute,
+	"/ISteamUser/GetFriendList/v0001/":                              10 * time.Minute,
+	"/ISteamUserStats/GetUserStatsForGame/v0002/":                   5 * time.Minute,
+	"/ISteamUserStats/GetPlayerAchievements/v0001/":                 5 * time.Minute,
+	"/ISteamUserStats/GetGlobalAchievementPercentagesForApp/v0002/": time.Hour,
+	"/ISteamUser/GetPlayerSummaries/v0002/":                         5 * time.Minute,
+	"/IPlayerService/GetRecentlyPlayedGames/v0001/":                 5 * time.Minute,
+	"/IPlayerService/GetSteamLevel/v1/":                             time.Hour,
+	"/ISteamUser/GetPlayerBans/v1/":                                 time.Hour,
+	"/IPlayerService/GetBadges/v1/":                                 time.Hour,
+	"/ISteamUser/ResolveVanityURL/v0001/":                           24 * time.Hour,
+}
+
+// cacheErasureRules has an entry for every path in DefaultCacheTTLs, saying
+// whether a response cached under it is about a given user, so erasure can
+// remove it. Paths whose responses are about no user in particular map to nil.
+var cacheErasureRules = map[string]func(query url.Values, value []byte, steamID string) bool{
+	"/ISteamUserStats/GetSchemaForGame/v2/":                         nil,
+	"/IPlayerService/GetOwnedGames/v0001/":                          requestedFor("steamid"),
+	"/ISteamUser/GetFriendList/v0001/":                              requestedFor("steamid"),
+	"/ISteamUserStats/GetUserStatsForGame/v0002/":                   requestedFor("steamid"),
+	"/ISteamUserStats/GetPlayerAchievements/v0001/":                 requestedFor("steamid"),
+	"/ISteamUserStats/GetGlobalAchievementPercentagesForApp/v0002/": nil,
+	"/ISteamUser/GetPlayerSummaries/v0002/":                         requestedFor("steamids"),
+	"/IPlayerService/GetRecentlyPlayedGames/v0001/":                 requestedFor("steamid"),
+	"/IPlayerService/GetSteamLevel/v1/":                             requestedFor("steamid"),
+	"/ISteamUser/GetPlayerBans/v1/":                                 requestedFor("steamids"),
+	"/IPlayerService/GetBadges/v1/":                                 requestedFor("steamid"),
+	"/ISteamUser/ResolveVanityURL/v0001/":                           resolvesTo,
+}
+
+// requestedFor matches responses whose request named the user in param,
+// which may hold a comma-separated list.
+func requestedFor(param string) func(url.Values, []byte, string) bool {
+	return func(query url.Values, _ []byte, steamID string) bool {
+		for _, id := range strings.Split(query.Get(param), ",") {
+			if id == steamID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// resolvesTo matches vanity name resolutions, which are keyed by name, by
+// the SteamID in the response.
+func resolvesTo(_ url.Values, value []byte, steamID string) bool {
+	var resp struct {
+		Response struct {
+			SteamID string `json:"steamid"`
+		} `json:"response"`
+	}
+	return json.Unmarshal(value, &resp) == nil && resp.Response.SteamID == steamID
+}
+
+// userCacheFilter selects the cached responses about steamID. Keys are the
+// path, "?" and the encoded query, as SteamClient.get builds them.
+func userCacheFilter(steamID string) store.CacheFilter {
+	var prefixes []string
+	for path, rule := range cacheErasureRules {
+		if rule != nil {
+			prefixes = append(prefixes, path+"?")
+		}
+	}
+	return store.CacheFilter{
+		Prefixes: prefixes,
+		Match: func(key string, value []byte) bool {
+			path, rawQuery, ok := strings.Cut(key, "?")
+			if !ok {
+				return false
+			}
+			rule := cacheErasureRules[path]
+			if rule == nil {
+				return false
+			}
+			query, err := url.ParseQuery(rawQuery)
+			return err == nil && rule(query, value, steamID)
+		},
+	}
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	// loadedAt is when the entry was last written or read from the persistent tier.
+	loadedAt time.Time
+}
+
+// ResponseCache is a two-tier cache for raw Steam API response bodies:
+// an in-memory LRU in front of an optional persistent store.
+//
+// The persistent tier may be shared by several instances. A memory entry
+// older than revalidate is re-read from it before being served, so entries
+// another instance deleted, say on erasure, stop being served within that.
+type ResponseCache struct {
+	mu         sync.Mutex
+	capacity   int
+	ll         *list.List
+	items      map[string]*list.Element
+	ttls       map[string]time.Duration
+	persistent store.CacheStore
+	revalidate time.Duration
+	now        func() time.Time
+}
+
+// NewResponseCache creates a cache holding at most capacity entries in memory.
+// persistent may be nil to run memory-only.
+func NewResponseCache(capacity int, persistent store.CacheStore) *ResponseCache {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for path, ttl := range DefaultCacheTTLs {
+		ttls[path] = ttl
+	}
+	return &ResponseCache{
+		capacity:   capacity,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		ttls:       ttls,
+		persistent: persistent,
+		revalidate: time.Minute,
+		now:        time.Now,
+	}
+}
+
+// SetTTL overrides the TTL for an endpoint path. A zero TTL disables caching for it.
+func (c *ResponseCache) SetTTL(path string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttls[path] = ttl
+}
+
+// TTL returns the configured TTL for an endpoint path.
+func (c *ResponseCache) TTL(path string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttls[path]
+}
+
+// Get returns the cached body for key, checking memory first and then the
+// persistent tier. Persistent hits are promoted into memory.
+func (c *ResponseCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	now := c.now()
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		fresh := c.persistent == nil || now.Sub(entry.loadedAt) < c.revalidate
+		if fresh && now.Before(entry.expiresAt) {
+			c.ll.MoveToFront(el)
+			c.mu.Unlock()
+			return entry.value, true
+		}
+		c.removeElement(el)
+	}
+	c.mu.Unlock()
+
+	if c.persistent == nil {
+		return nil, false
+	}
+
+	value, expiresAt, err := c.persistent.GetCacheEntry(ctx, key)
+	if err != nil {
+		log.Printf("cache: persistent read failed for %s: %v", key, err)
+		return nil, false
+	}
+	if value == nil {
+		return nil, false
+	}
+	if !now.Before(expiresAt) {
+		if err := c.persistent.DeleteCacheEntry(ctx, key); err != nil {
+			log.Printf("cache: persistent delete failed for %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	c.mu.Lock()
+	c.add(key, value, expiresAt, now)
+	c.mu.Unlock()
+	return value, true
+}
+
+// Set stores body under key in both tiers for ttl.
+func (c *ResponseCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	now := c.now()
+	expiresAt := now.Add(ttl)
+
+	c.mu.Lock()
+	c.add(key, value, expiresAt, now)
+	c.mu.Unlock()
+
+	if c.persistent != nil {
+		if err := c.persistent.SetCacheEntry(ctx, key, value, expiresAt); err != nil {
+			log.Printf("cache: persistent write failed for %s: %v", key, err)
+		}
+	}
+}
+
+// ForgetMemory drops every in-memory entry for which match returns true and
+// returns how many it dropped. The persistent tier is left to the caller;
+// other instances drop their copies once they revalidate them against it.
+func (c *ResponseCache) ForgetMemory(match func(key string, value []byte) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for key, el := range c.items {
+		if match(key, el.Value.(*cacheEntry).value) {
+			c.removeElement(el)
+			n++
+		}
+	}
+	return n
+}
+
+// add must be called with c.mu held.
+func (c *ResponseCache) add(key string, value []byte, expiresAt, loadedAt time.Time) {
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		entry.loadedAt = loadedAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt, loadedAt: loadedAt})
+	c.items[key] = el
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// removeElement must be called with c.mu held.
+func (c *ResponseCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"backend/internal/store"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCacheReplicasRevalidateAgainstPersistentTier(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLiteStore(t)
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	// Two instances sharing one database.
+	a, b := NewResponseCache(10, db), NewResponseCache(10, db)
+	a.now, b.now = clock, clock
+
+	const key = "/ISteamUser/GetPlayerSummaries/v0002/?steamids=76561197960287930"
+	a.Set(ctx, key, []byte("summary"), time.Hour)
+	if v, ok := b.Get(ctx, key); !ok || string(v) != "summary" {
+		t.Fatalf("b.Get = %q, %v; want the entry a stored", v, ok)
+	}
+
+	// a erases the entry from memory and the shared tier.
+	a.ForgetMemory(func(k string, _ []byte) bool { return k == key })
+	if err := db.DeleteCacheEntry(ctx, key); err != nil {
+		t.Fatalf("DeleteCacheEntry: %v", err)
+	}
+
+	now = now.Add(b.revalidate - time.Second)
+	if _, ok := b.Get(ctx, key); !ok {
+		t.Errorf("b.Get before revalidating missed; want its memory copy")
+	}
+	now = now.Add(time.Second)
+	if v, ok := b.Get(ctx, key); ok {
+		t.Errorf("b.Get after revalidating = %q; want a miss once the shared entry is gone", v)
+	}
+	if _, ok := a.Get(ctx, key); ok {
+		t.Errorf("a.Get hit after ForgetMemory")
+	}
+
+	// Entries still in the shared tier are re-read and kept.
+	a.Set(ctx, key, []byte("again"), time.Hour)
+	if _, ok := b.Get(ctx, key); !ok {
+		t.Fatalf("b.Get missed a stored entry")
+	}
+	now = now.Add(b.revalidate)
+	if v, ok := b.Get(ctx, key); !ok || string(v) != "again" {
+		t.Errorf("b.Get after revalidating = %q, %v; want the shared entry", v, ok)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewResponseCache(2, nil)
+	c.Set(ctx, "a", []byte("a"), time.Hour)
+	c.Set(ctx, "b", []byte("b"), time.Hour)
+	// Reading a makes b the least recently used.
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatal("Get(a) missed")
+	}
+	c.Set(ctx, "c", []byte("c"), time.Hour)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(ctx, key); ok != want {
+			t.Errorf("Get(%s) hit = %v, want %v", key, ok, want)
+		}
+	}
+	if n := c.ll.Len(); n != 2 {
+		t.Errorf("cache holds %d entries, want its capacity of 2", n)
+	}
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	c := NewResponseCache(10, nil)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "key", []byte("value"), time.Minute)
+	c.Set(ctx, "zero", []byte("value"), 0)
+	if _, ok := c.Get(ctx, "zero"); ok {
+		t.Error("Get hit an entry set with a zero TTL")
+	}
+
+	now = now.Add(time.Minute - time.Second)
+	if _, ok := c.Get(ctx, "key"); !ok {
+		t.Fatal("Get missed before the TTL ran out")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get(ctx, "key"); ok {
+		t.Error("Get hit once the TTL ran out")
+	}
+	if _, ok := c.items["key"]; ok {
+		t.Error("expired entry still held in memory")
+	}
+}
+
+func TestCachePromotesPersistentEntries(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLiteStore(t)
+	now := time.Unix(1700000000, 0)
+	if err := db.SetCacheEntry(ctx, "key", []byte("stored"), now.Add(time.Hour)); err != nil {
+		t.Fatalf("SetCacheEntry: %v", err)
+	}
+	c := NewResponseCache(10, db)
+	c.now = func() time.Time { return now }
+
+	if v, ok := c.Get(ctx, "key"); !ok || string(v) != "stored" {
+		t.Fatalf("Get = %q, %v; want the persistent entry", v, ok)
+	}
+	el, ok := c.items["key"]
+	if !ok {
+		t.Fatal("persistent hit not promoted into memory")
+	}
+	if got := el.Value.(*cacheEntry).expiresAt; !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("promoted entry expires at %v, want the persistent expiry %v", got, now.Add(time.Hour))
+	}
+
+	// Until it revalidates, the promoted entry is served from memory alone.
+	if err := db.DeleteCacheEntry(ctx, "key"); err != nil {
+		t.Fatalf("DeleteCacheEntry: %v", err)
+	}
+	if _, ok := c.Get(ctx, "key"); !ok {
+		t.Error("Get missed the promoted entry")
+	}
+}
+
+func TestCacheDropsExpiredPersistentEntries(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLiteStore(t)
+	now := time.Unix(1700000000, 0)
+	c := NewResponseCache(10, db)
+	c.now = func() time.Time { return now }
+
+	for key, expiresAt := range map[string]time.Time{
+		"expired": now.Add(-time.Minute),
+		"stale":   now.Add(-time.Hour),
+		"fresh":   now.Add(time.Hour),
+	} {
+		if err := db.SetCacheEntry(ctx, key, []byte(key), expiresAt); err != nil {
+			t.Fatalf("SetCacheEntry(%s): %v", key, err)
+		}
+	}
+
+	// A read that finds an expired row misses and deletes it.
+	if v, ok := c.Get(ctx, "expired"); ok {
+		t.Errorf("Get(expired) = %q; want a miss", v)
+	}
+	if v, _, err := db.GetCacheEntry(ctx, "expired"); err != nil || v != nil {
+		t.Errorf("expired row after Get = %q, %v; want it deleted", v, err)
+	}
+	if _, ok := c.items["expired"]; ok {
+		t.Error("expired row promoted into memory")
+	}
+
+	// The rest are left to the periodic purge.
+	n, err := db.PurgeExpiredCacheEntries(ctx, now)
+	if err != nil || n != 1 {
+		t.Errorf("PurgeExpiredCacheEntries = %d, %v; want 1 row", n, err)
+	}
+	if v, _, err := db.GetCacheEntry(ctx, "stale"); err != nil || v != nil {
+		t.Errorf("stale row after purge = %q, %v; want it deleted", v, err)
+	}
+	if v, _, err := db.GetCacheEntry(ctx, "fresh"); err != nil || string(v) != "fresh" {
+		t.Errorf("fresh row after purge = %q, %v; want it kept", v, err)
+	}
+}
+
+func TestSteamClientWithoutCacheSkipsCachedResponses(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int64
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		fmt.Fprintf(w, `{"response": {"players": [{"steamid": %q, "personaname": "name %d"}]}}`,
+			r.URL.Query().Get("steamids"), n)
+	}))
+	defer steam.Close()
+
+	client := NewSteamClient("key", WithBaseURL(steam.URL), WithCache(NewResponseCache(10, nil)))
+	name := func(c *SteamClient) string {
+		t.Helper()
+		user, err := c.GetUserSummary(ctx, schedulerSteamID)
+		if err != nil {
+			t.Fatalf("GetUserSummary: %v", err)
+		}
+		return user.PersonName
+	}
+
+	if got := name(client); got != "name 1" {
+		t.Fatalf("first call = %q, want name 1", got)
+	}
+	if got := name(client); got != "name 1" || requests.Load() != 1 {
+		t.Errorf("cached call = %q after %d requests, want name 1 from the cache", got, requests.Load())
+	}
+	if got := name(client.WithoutCache()); got != "name 2" || requests.Load() != 2 {
+		t.Errorf("uncached call = %q after %d requests, want name 2 from Steam", got, requests.Load())
+	}
+	// The uncached response still refreshes the cache for everyone else.
+	if got := name(client); got != "name 2" || requests.Load() != 2 {
+		t.Errorf("cached call after refresh = %q after %d requests, want name 2 from the cache", got, requests.Load())
+	}
+}
+
+func TestCacheErasureRulesCoverEveryCachedPath(t *testing.T) {
+	// A path cached without a rule would outlive the erasure of its users.
+	for path := range DefaultCacheTTLs {
+		if _, ok := cacheErasureRules[path]; !ok {
+			t.Errorf("%s is cached but has no erasure rule; add one, nil if its responses are about no user", path)
+		}
+	}
+	for path := range cacheErasureRules {
+		if _, ok := DefaultCacheTTLs[path]; !ok {
+			t.Errorf("erasure rule for %s, which is not cached", path)
+		}
+	}
+}
+
+func TestUserCacheFilter(t *testing.T) {
+	const steamID = "76561197960287930"
+	filter := userCacheFilter(steamID)
+	tests := []struct {
+		key   string
+		value string
+		want  bool
+	}{
+		{"/IPlayerService/GetOwnedGames/v0001/?include_appinfo=1&steamid=" + steamID, "{}", true},
+		{"/IPlayerService/GetOwnedGames/v0001/?steamid=" + steamID + "1", "{}", false},
+		{"/ISteamUser/GetPlayerSummaries/v0002/?steamids=76561197960287931%2C" + steamID, "{}", true},
+		{"/ISteamUser/GetPlayerSummaries/v0002/?steamids=76561197960287931", "{}", false},
+		{"/ISteamUser/ResolveVanityURL/v0001/?vanityurl=gabe", `{"response":{"steamid":"` + steamID + `","success":1}}`, true},
+		{"/ISteamUser/ResolveVanityURL/v0001/?vanityurl=other", `{"response":{"steamid":"76561197960287931","success":1}}`, false},
+		{"/ISteamUserStats/GetSchemaForGame/v2/?appid=440", `{"game":{"gameName":"` + steamID + `"}}`, false},
+		{"/ISteamUser/GetPlayerSummaries/v0002/", "{}", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Match(tt.key, []byte(tt.value)); got != tt.want {
+			t.Errorf("Match(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	for _, prefix := range filter.Prefixes {
+		if cacheErasureRules[strings.TrimSuffix(prefix, "?")] == nil {
+			t.Errorf("filter reads %s, which holds no user's responses", prefix)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrUnknownApp is returned for apps that are neither in the catalog nor in
+// anyone's library.
+var ErrUnknownApp = errors.New("app is not in the catalog")
+
+// CatalogService keeps a local catalog of storefront metadata (genres,
+// developers, artwork, ...) for owned apps and refreshes it in the background.
+type CatalogService struct {
+	store       store.CatalogStore
+	library     store.LibraryStore
+	steamClient *SteamClient
+	// RefreshAfter is how long fetched metadata stays fresh.
+	RefreshAfter time.Duration
+	// RetryAfter is how long to wait before retrying an app whose fetch failed.
+	RetryAfter time.Duration
+	// BatchSize caps how many apps one background pass fetches.
+	BatchSize int
+	now       func() time.Time
+}
+
+func NewCatalogService(store store.CatalogStore, library store.LibraryStore, steamClient *SteamClient) *CatalogService {
+	return &CatalogService{
+		store:        store,
+		library:      library,
+		steamClient:  steamClient,
+		RefreshAfter: 7 * 24 * time.Hour,
+		RetryAfter:   time.Hour,
+		BatchSize:    20,
+		now:          time.Now,
+	}
+}
+
+// GetMetadata returns catalog metadata for appID. It never calls the store
+// API inline: an app someone owns that has not been fetched yet is queued
+// for the background refresher and comes back as nil. Apps nobody owns are
+// not added, so callers can't grow the catalog with arbitrary IDs.
+func (c *CatalogService) GetMetadata(ctx context.Context, appID int) (*models.GameMetadata, error) {
+	if appID <= 0 {
+		return nil, ErrUnknownApp
+	}
+	entries, err := c.store.GetCatalogEntries(ctx, []int{appID})
+	if err != nil {
+		return nil, err
+	}
+	if meta, ok := entries[appID]; ok {
+		return meta, nil
+	}
+
+	tracked, err := c.store.HasCatalogEntry(ctx, appID)
+	if err != nil || tracked {
+		return nil, err
+	}
+	owned, err := c.library.IsAppOwned(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrUnknownApp
+	}
+	return nil, c.store.EnsureCatalogEntries(ctx, []int{appID}, c.now())
+}
+
+// Refresh fetches appID from the storefront now and saves it.
+func (c *CatalogService) Refresh(ctx context.Context, appID int) (*models.GameMetadata, error) {
+	details, err := c.steamClient.GetAppDetails(ctx, appID)
+	if err != nil {
+		if err := c.store.EnsureCatalogEntries(ctx, []int{appID}, c.now()); err != nil {
+			log.Printf("catalog: failed to track app %d: %v", appID, err)
+		}
+		if err := c.store.ScheduleCatalogRefresh(ctx, appID, c.now().Add(c.RetryAfter)); err != nil {
+			log.Printf("catalog: failed to reschedule app %d: %v", appID, err)
+		}
+		return nil, err
+	}
+
+	meta := metadataFromDetails(appID, details)
+	meta.FetchedAt = c.now()
+	if err := c.store.SaveCatalogEntry(ctx, meta, meta.FetchedAt.Add(c.RefreshAfter)); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// EnrichLibrary attaches catalog metadata to owned games. It never calls the
+// store API inline: apps not in the catalog yet are queued for the background
+// refresher and come back with nil metadata.
+func (c *CatalogService) EnrichLibrary(ctx context.Context, games []models.SteamGame) ([]models.LibraryGame, error) {
+	appIDs := make([]int, len(games))
+	for i, g := range games {
+		appIDs[i] = g.AppID
+	}
+
+	entries, err := c.store.GetCatalogEntries(ctx, appIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []int
+	result := make([]models.LibraryGame, len(games))
+	for i, g := range games {
+		result[i] = models.LibraryGame{SteamGame: g, Metadata: entries[g.AppID]}
+		if entries[g.AppID] == nil {
+			missing = append(missing, g.AppID)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := c.store.EnsureCatalogEntries(ctx, missing, c.now()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Run refreshes due catalog entries every interval until ctx is cancelled.
+func (c *CatalogService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.refreshDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *CatalogService) refreshDue(ctx context.Context) {
+	due, err := c.store.ListCatalogDue(ctx, c.now(), c.BatchSize)
+	if err != nil {
+		log.Printf("catalog: failed to list due entries: %v", err)
+		return
+	}
+	for _, appID := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := c.Refresh(ctx, appID); err != nil {
+			log.Printf("catalog: refresh of app %d failed: %v", appID, err)
+		}
+	}
+}
+
+func metadataFromDetails(appID int, details *models.AppDetails) *models.GameMetadata {
+	if details == nil {
+		return &models.GameMetadata{AppID: appID, Unavailable: true}
+	}
+
+	meta := &models.GameMetadata{
+		AppID:            appID,
+		Name:             details.Name,
+		Type:             details.Type,
+		ShortDescription: details.ShortDescription,
+		HeaderImage:      details.HeaderImage,
+		Developers:       details.Developers,
+		Publishers:       details.Publishers,
+		ReleaseDate:      details.ReleaseDate.Date,
+		ComingSoon:       details.ReleaseDate.ComingSoon,
+		IsFree:           details.IsFree,
+	}
+	for _, g := range details.Genres {
+		meta.Genres = append(meta.Genres, g.Description)
+	}
+	for _, c := range details.Categories {
+		meta.Categories = append(meta.Categories, c.Description)
+	}
+	return meta
+}
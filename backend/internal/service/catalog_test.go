@@ -0,0 +1,128 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCatalogGetMetadataNeverFetchesInline(t *testing.T) {
+	ctx := context.Background()
+	var fetches atomic.Int32
+	storefront := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		appID := r.URL.Query().Get("appids")
+		fmt.Fprintf(w, `{"%s": {"success": true, "data": {"name": "Game %s", "type": "game"}}}`, appID, appID)
+	}))
+	defer storefront.Close()
+
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer db.Close()
+	now := time.Unix(1700000000, 0)
+	err = db.UpdateLibrary(ctx, "76561197960287930", func(map[int]*models.LibraryEntry) ([]models.LibraryEntry, []models.LibraryEvent) {
+		return []models.LibraryEntry{
+			{AppID: 440, Name: "Team Fortress 2", FirstSeen: now, LastSeen: now},
+			{AppID: 570, Name: "Dota 2", FirstSeen: now, LastSeen: now, Removed: true},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateLibrary: %v", err)
+	}
+
+	c := NewCatalogService(db, db, NewSteamClient("key", WithStoreBaseURL(storefront.URL)))
+	c.now = func() time.Time { return now }
+
+	for _, appID := range []int{0, -440, 999999, 570} {
+		if _, err := c.GetMetadata(ctx, appID); !errors.Is(err, ErrUnknownApp) {
+			t.Errorf("GetMetadata(%d) error = %v, want ErrUnknownApp", appID, err)
+		}
+		if tracked, _ := db.HasCatalogEntry(ctx, appID); tracked {
+			t.Errorf("GetMetadata(%d) added an unowned app to the catalog", appID)
+		}
+	}
+
+	// An owned app is queued rather than fetched.
+	meta, err := c.GetMetadata(ctx, 440)
+	if err != nil || meta != nil {
+		t.Fatalf("GetMetadata(440) = %v, %v; want nil, nil while queued", meta, err)
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Fatalf("GetMetadata made %d storefront requests, want 0", n)
+	}
+
+	c.refreshDue(ctx)
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("background refresh made %d storefront requests, want 1", n)
+	}
+	meta, err = c.GetMetadata(ctx, 440)
+	if err != nil || meta == nil || meta.Name != "Game 440" {
+		t.Fatalf("GetMetadata(440) after refresh = %+v, %v", meta, err)
+	}
+}
+
+func TestCatalogRefetchesUndecodableEntries(t *testing.T) {
+	ctx := context.Background()
+	var fetches atomic.Int32
+	storefront := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		appID := r.URL.Query().Get("appids")
+		fmt.Fprintf(w, `{"%s": {"success": true, "data": {"name": "Game %s", "type": "game"}}}`, appID, appID)
+	}))
+	defer storefront.Close()
+
+	path := filepath.Join(t.TempDir(), "steam_data.db")
+	db, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer db.Close()
+	now := time.Unix(1700000000, 0)
+	c := NewCatalogService(db, db, NewSteamClient("key", WithStoreBaseURL(storefront.URL)))
+	c.now = func() time.Time { return now }
+	for _, appID := range []int{440, 570} {
+		if _, err := c.Refresh(ctx, appID); err != nil {
+			t.Fatalf("Refresh(%d): %v", appID, err)
+		}
+	}
+
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.ExecContext(ctx, `UPDATE game_catalog SET data = '{"appId": 570, "na' WHERE app_id = 570`); err != nil {
+		t.Fatalf("corrupting entry: %v", err)
+	}
+
+	// The library keeps serving the other entries.
+	games := []models.SteamGame{{AppID: 440}, {AppID: 570}}
+	library, err := c.EnrichLibrary(ctx, games)
+	if err != nil {
+		t.Fatalf("EnrichLibrary: %v", err)
+	}
+	if library[0].Metadata == nil || library[0].Metadata.Name != "Game 440" || library[1].Metadata != nil {
+		t.Errorf("EnrichLibrary = %+v, %+v; want 440's metadata and none for 570", library[0].Metadata, library[1].Metadata)
+	}
+
+	// The next pass fetches the undecodable entry again.
+	fetches.Store(0)
+	c.refreshDue(ctx)
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("refresh pass fetched %d apps, want 1", n)
+	}
+	if meta, err := c.GetMetadata(ctx, 570); err != nil || meta == nil || meta.Name != "Game 570" {
+		t.Errorf("GetMetadata(570) after refresh = %+v, %v", meta, err)
+	}
+}
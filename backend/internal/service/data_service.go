@@ -3,6 +3,8 @@ package service
 import (
 	"backend/internal/models"
 	"backend/internal/store"
+	"context"
+	"time"
 )
 
 type DataService struct {
@@ -17,22 +19,37 @@ func NewDataService(store store.Store, steamClient *SteamClient) *DataService {
 	}
 }
 
-func (s *DataService) SaveGameData(steamID string, data *models.LocalGameData) error {
-	return s.store.SaveGameData(steamID, data)
+// SaveGameData stores data, stamping it as updated now. CreatedAt is kept
+// from the first save.
+func (s *DataService) SaveGameData(ctx context.Context, steamID string, data *models.LocalGameData) error {
+	now := time.Now()
+	data.CreatedAt = now
+	data.UpdatedAt = now
+	return s.store.SaveGameData(ctx, steamID, data)
 }
 
-func (s *DataService) GetGameData(steamID string, appID int) (*models.LocalGameData, error) {
-	return s.store.GetGameData(steamID, appID)
+// DeleteGameData removes the game's data. Deleting data that doesn't exist
+// is not an error.
+func (s *DataService) DeleteGameData(ctx context.Context, steamID string, appID int) error {
+	return s.store.DeleteGameData(ctx, steamID, appID)
 }
 
-func (s *DataService) GetAllGameData(steamID string) (map[int]*models.LocalGameData, error) {
-	return s.store.GetAllGameData(steamID)
+func (s *DataService) GetGameData(ctx context.Context, steamID string, appID int) (*models.LocalGameData, error) {
+	return s.store.GetGameData(ctx, steamID, appID)
+}
+
+func (s *DataService) GetAllGameData(ctx context.Context, steamID string) (map[int]*models.LocalGameData, error) {
+	return s.store.GetAllGameData(ctx, steamID)
+}
+
+func (s *DataService) QueryGameData(ctx context.Context, steamID string, q store.GameDataQuery) ([]*models.LocalGameData, error) {
+	return s.store.QueryGameData(ctx, steamID, q)
 }
 
 // RegisterOrUpdateUser fetches user info from Steam and saves/updates it in local store.
-func (s *DataService) RegisterOrUpdateUser(steamID string) (*models.SteamUser, error) {
+func (s *DataService) RegisterOrUpdateUser(ctx context.Context, steamID string) (*models.SteamUser, error) {
 	// 1. Fetch from Steam
-	user, err := s.steamClient.GetUserSummary(steamID)
+	user, err := s.steamClient.GetUserSummary(ctx, steamID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,14 +58,25 @@ func (s *DataService) RegisterOrUpdateUser(steamID string) (*models.SteamUser, e
 	}
 
 	// 2. Save to DB
-	if err := s.store.SaveUser(user); err != nil {
+	if err := s.store.SaveUser(ctx, user); err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
+// LookupUser fetches user info from Steam without saving it. It returns nil
+// if Steam has no such user.
+func (s *DataService) LookupUser(ctx context.Context, steamID string) (*models.SteamUser, error) {
+	return s.steamClient.GetUserSummary(ctx, steamID)
+}
+
+// ResolveSteamID normalizes a SteamID, profile link or vanity name to a SteamID64.
+func (s *DataService) ResolveSteamID(ctx context.Context, input string) (string, error) {
+	return s.steamClient.ResolveSteamID(ctx, input)
+}
+
 // GetUser returns the user from local store.
-func (s *DataService) GetUser(steamID string) (*models.SteamUser, error) {
-	return s.store.GetUser(steamID)
+func (s *DataService) GetUser(ctx context.Context, steamID string) (*models.SteamUser, error) {
+	return s.store.GetUser(ctx, steamID)
 }
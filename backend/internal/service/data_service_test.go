@@ -0,0 +1,42 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"testing"
+)
+
+func TestDataServiceSaveGameDataKeepsCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	s := NewDataService(store.NewMemoryStore(), nil)
+
+	if err := s.SaveGameData(ctx, "76561197960287930", &models.LocalGameData{AppID: 440}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	first, err := s.GetGameData(ctx, "76561197960287930", 440)
+	if err != nil || first == nil {
+		t.Fatalf("GetGameData = %v, %v", first, err)
+	}
+	if first.CreatedAt.IsZero() || !first.UpdatedAt.Equal(first.CreatedAt) {
+		t.Errorf("first save stamped created %v, updated %v; want both now", first.CreatedAt, first.UpdatedAt)
+	}
+
+	notes := "second save"
+	if err := s.SaveGameData(ctx, "76561197960287930", &models.LocalGameData{AppID: 440, Notes: &notes}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	second, err := s.GetGameData(ctx, "76561197960287930", 440)
+	if err != nil || second == nil {
+		t.Fatalf("GetGameData = %v, %v", second, err)
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("CreatedAt changed from %v to %v", first.CreatedAt, second.CreatedAt)
+	}
+	if second.UpdatedAt.Before(first.UpdatedAt) {
+		t.Errorf("UpdatedAt went back from %v to %v", first.UpdatedAt, second.UpdatedAt)
+	}
+	if second.Notes == nil || *second.Notes != notes {
+		t.Errorf("Notes = %v, want %q", second.Notes, notes)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors returned (wrapped) by SteamClient. Match them with errors.Is.
+var (
+	ErrPrivateProfile      = errors.New("steam profile is private")
+	ErrNoStats             = errors.New("game has no stats")
+	ErrUnauthorizedKey     = errors.New("steam api key was rejected")
+	ErrRateLimited         = errors.New("steam rate limit exceeded")
+	ErrUpstreamUnavailable = errors.New("steam api is unavailable")
+)
+
+// SteamAPIError describes a failed Steam Web API call.
+type SteamAPIError struct {
+	Path       string
+	StatusCode int // 0 if no response was received
+	// RetryAfter is the server-suggested wait, if Steam sent one.
+	RetryAfter time.Duration
+	// Kind is one of the sentinel errors above, or nil for unclassified failures.
+	Kind  error
+	cause error
+}
+
+func (e *SteamAPIError) Error() string {
+	msg := "steam api error: " + e.Path
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(": status %d", e.StatusCode)
+	}
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+func (e *SteamAPIError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// classifyStatus maps an upstream HTTP failure to one of the sentinel errors.
+// body is the (possibly truncated) response body, used to tell private
+// profiles and stat-less games apart from key problems.
+func classifyStatus(path string, status int, body []byte) error {
+	text := strings.ToLower(string(body))
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrUpstreamUnavailable
+	case strings.Contains(text, "not public") || strings.Contains(text, "private"):
+		return ErrPrivateProfile
+	case strings.Contains(text, "no stats"):
+		return ErrNoStats
+	case status == http.StatusUnauthorized && strings.HasPrefix(path, "/ISteamUser/GetFriendList/"):
+		// GetFriendList answers 401 when the friends list is hidden.
+		return ErrPrivateProfile
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorizedKey
+	}
+	return nil
+}
+
+// classifyStatsError maps the error string Steam embeds in a 200 playerstats
+// payload (success=false) to a sentinel error.
+func classifyStatsError(msg string) error {
+	text := strings.ToLower(msg)
+	switch {
+	case strings.Contains(text, "not public") || strings.Contains(text, "private"):
+		return ErrPrivateProfile
+	case strings.Contains(text, "no stats"):
+		return ErrNoStats
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+		want   error
+	}{
+		{"rate limited", "/IPlayerService/GetOwnedGames/v0001/", http.StatusTooManyRequests, "", ErrRateLimited},
+		{"server error", "/IPlayerService/GetOwnedGames/v0001/", http.StatusInternalServerError, "", ErrUpstreamUnavailable},
+		{"unavailable", "/IPlayerService/GetOwnedGames/v0001/", http.StatusServiceUnavailable, "Service Unavailable", ErrUpstreamUnavailable},
+		{"bad key", "/IPlayerService/GetOwnedGames/v0001/", http.StatusUnauthorized, "Unauthorized", ErrUnauthorizedKey},
+		{"forbidden key", "/IPlayerService/GetOwnedGames/v0001/", http.StatusForbidden, "Forbidden", ErrUnauthorizedKey},
+		{"private profile", "/ISteamUserStats/GetPlayerAchievements/v0001/", http.StatusForbidden, `{"playerstats":{"error":"Profile is not public","success":false}}`, ErrPrivateProfile},
+		{"no stats", "/ISteamUserStats/GetPlayerAchievements/v0001/", http.StatusBadRequest, `{"playerstats":{"error":"Requested app has no stats","success":false}}`, ErrNoStats},
+		{"hidden friend list", "/ISteamUser/GetFriendList/v0001/", http.StatusUnauthorized, "Unauthorized", ErrPrivateProfile},
+		{"unclassified", "/IPlayerService/GetOwnedGames/v0001/", http.StatusBadRequest, "Bad Request", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyStatus(tt.path, tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("classifyStatus(%d, %q) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyStatsError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want error
+	}{
+		{"Profile is not public", ErrPrivateProfile},
+		{"This profile is private.", ErrPrivateProfile},
+		{"Requested app has no stats", ErrNoStats},
+		{"", nil},
+		{"Unknown problem", nil},
+	}
+	for _, tt := range tests {
+		if got := classifyStatsError(tt.msg); got != tt.want {
+			t.Errorf("classifyStatsError(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestGetPlayerAchievementsClassifiesFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"private in a 200 body", http.StatusOK, `{"playerstats": {"error": "Profile is not public", "success": false}}`, ErrPrivateProfile},
+		{"no stats in a 200 body", http.StatusOK, `{"playerstats": {"steamID": "76561197960287930", "error": "Requested app has no stats", "success": false}}`, ErrNoStats},
+		{"empty SteamID", http.StatusOK, `{"playerstats": {"success": false}}`, ErrPrivateProfile},
+		{"private in a 403 body", http.StatusForbidden, `{"playerstats": {"error": "Profile is not public", "success": false}}`, ErrPrivateProfile},
+		{"bad key", http.StatusUnauthorized, "Unauthorized", ErrUnauthorizedKey},
+		{"forbidden key", http.StatusForbidden, "Forbidden", ErrUnauthorizedKey},
+		{"rate limited", http.StatusTooManyRequests, "", ErrRateLimited},
+		{"server error", http.StatusBadGateway, "", ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer steam.Close()
+			client := NewSteamClient("key", WithBaseURL(steam.URL), WithRetryPolicy(RetryPolicy{}))
+
+			_, err := client.GetPlayerAchievements(context.Background(), "76561197960287930", 440, AchievementOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("GetPlayerAchievements error = %v, want %v", err, tt.want)
+			}
+			var apiErr *SteamAPIError
+			if tt.status != http.StatusOK && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.status) {
+				t.Errorf("error = %v, want a SteamAPIError with status %d", err, tt.status)
+			}
+		})
+	}
+}
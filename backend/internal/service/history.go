@@ -0,0 +1,50 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+)
+
+// ErrRevisionNotFound is returned for revisions a game doesn't have.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// HistoryService serves the history of a user's game data, which the store
+// records on every save, and restores earlier revisions.
+type HistoryService struct {
+	store store.HistoryStore
+	data  *DataService
+}
+
+func NewHistoryService(store store.HistoryStore, data *DataService) *HistoryService {
+	return &HistoryService{store: store, data: data}
+}
+
+// History returns the game's revisions, newest first.
+func (h *HistoryService) History(ctx context.Context, steamID string, appID int) ([]models.GameDataRevision, error) {
+	return h.store.ListGameDataHistory(ctx, steamID, appID)
+}
+
+// Restore saves the game data as it was after revision. The restore is a
+// change like any other and is recorded as a new revision. Restoring a
+// deletion deletes the data again and returns nil.
+func (h *HistoryService) Restore(ctx context.Context, steamID string, appID int, revision int64) (*models.LocalGameData, error) {
+	rev, err := h.store.GetGameDataRevision(ctx, steamID, appID, revision)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, ErrRevisionNotFound
+	}
+	if rev.Data == nil {
+		return nil, h.data.DeleteGameData(ctx, steamID, appID)
+	}
+
+	data := rev.Data
+	data.AppID = appID
+	if err := h.data.SaveGameData(ctx, steamID, data); err != nil {
+		return nil, err
+	}
+	return h.data.GetGameData(ctx, steamID, appID)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHistoryServiceRestore(t *testing.T) {
+	ctx := context.Background()
+	const steamID = "76561197960287930"
+	memory := store.NewMemoryStore()
+	data := NewDataService(memory, nil)
+	history := NewHistoryService(memory, data)
+
+	for _, notes := range []string{"months of notes", "oops"} {
+		if err := data.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: 440, Notes: &notes}); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	revisions, err := history.History(ctx, steamID, 440)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("History = %d revisions, %v; want 2", len(revisions), err)
+	}
+
+	restored, err := history.Restore(ctx, steamID, 440, revisions[1].Revision)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.Notes == nil || *restored.Notes != "months of notes" {
+		t.Errorf("restored notes = %v, want %q", restored.Notes, "months of notes")
+	}
+
+	// The restore itself is recorded, so it can be undone too.
+	revisions, err = history.History(ctx, steamID, 440)
+	if err != nil || len(revisions) != 3 {
+		t.Fatalf("History after restore = %d revisions, %v; want 3", len(revisions), err)
+	}
+	if prev := revisions[0].Previous; prev == nil || prev.Notes == nil || *prev.Notes != "oops" {
+		t.Errorf("restore revision's previous value = %+v, want notes %q", prev, "oops")
+	}
+
+	if _, err := history.Restore(ctx, steamID, 440, 999); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Restore of unknown revision = %v, want ErrRevisionNotFound", err)
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned for unknown job IDs.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobFunc runs one attempt of a job. Calling report records progress, which
+// is saved with the next lease renewal. Returning an error wrapped with
+// Permanent stops retries.
+type JobFunc func(ctx context.Context, job *models.Job, report func(progress interface{})) (result interface{}, err error)
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// JobQueue runs jobs from the persistent queue. Jobs are leased while they
+// run and the lease is renewed in the background; a job whose lease expires,
+// because the server stopped, is picked up again by the next worker.
+type JobQueue struct {
+	store    store.JobStore
+	handlers map[string]JobFunc
+
+	// Workers is how many jobs run at once.
+	Workers int
+	// LeaseDuration is how long a worker holds a job between renewals.
+	LeaseDuration time.Duration
+	// PollInterval is how often idle workers look for due jobs.
+	PollInterval time.Duration
+	// MaxAttempts is the default number of attempts before a job is dead.
+	MaxAttempts int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential retry backoff.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Retention is how long completed and dead jobs are kept after they
+	// finish. Zero keeps them forever.
+	Retention time.Duration
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+func NewJobQueue(store store.JobStore) *JobQueue {
+	return &JobQueue{
+		store:          store,
+		handlers:       make(map[string]JobFunc),
+		Workers:        2,
+		LeaseDuration:  time.Minute,
+		PollInterval:   2 * time.Second,
+		MaxAttempts:    5,
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  time.Hour,
+		Retention:      7 * 24 * time.Hour,
+		wake:           make(chan struct{}, 1),
+		now:            time.Now,
+	}
+}
+
+// Register sets the function that runs jobs of jobType. It must be called
+// before Run.
+func (q *JobQueue) Register(jobType string, fn JobFunc) {
+	q.handlers[jobType] = fn
+}
+
+// Enqueue adds a job for steamID. If one of the same type is already queued
+// or running for the user, that job is returned instead. The store refuses a
+// second active job, so concurrent calls agree on a single job.
+func (q *JobQueue) Enqueue(ctx context.Context, jobType, steamID string, payload interface{}) (*models.Job, error) {
+	if _, ok := q.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+	for {
+		existing, err := q.store.FindActiveJob(ctx, jobType, steamID)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+		job, err := q.create(ctx, jobType, steamID, payload)
+		// Another caller created the job since FindActiveJob; look again.
+		if errors.Is(err, store.ErrActiveJobExists) {
+			continue
+		}
+		return job, err
+	}
+}
+
+func (q *JobQueue) create(ctx context.Context, jobType, steamID string, payload interface{}) (*models.Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	now := q.now()
+	job := &models.Job{
+		ID:          id,
+		Type:        jobType,
+		SteamID:     steamID,
+		Status:      models.JobQueued,
+		MaxAttempts: q.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if payload != nil {
+		if job.Payload, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	if err := q.store.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get returns the job with the given ID.
+func (q *JobQueue) Get(ctx context.Context, id string) (*models.Job, error) {
+	job, err := q.store.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Run processes jobs until ctx is cancelled. Jobs interrupted by the
+// cancellation go back to the queue without using up an attempt. Finished
+// jobs are purged hourly once they are older than Retention.
+func (q *JobQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range max(q.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			q.purge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Hour):
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// purge deletes jobs that finished more than Retention ago.
+func (q *JobQueue) purge(ctx context.Context) {
+	if q.Retention <= 0 {
+		return
+	}
+	n, err := q.store.PurgeJobs(ctx, q.now().Add(-q.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("jobs: purging finished jobs: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		log.Printf("jobs: purged %d finished job(s)", n)
+	}
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := q.runNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("jobs: %v", err)
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-q.wake:
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// runNext leases and runs one job, reporting whether there was one.
+func (q *JobQueue) runNext(ctx context.Context) (bool, error) {
+	leaseID, err := newJobID()
+	if err != nil {
+		return false, err
+	}
+	job, err := q.store.LeaseJob(ctx, q.now(), leaseID, q.now().Add(q.LeaseDuration))
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	result, runErr := q.attempt(ctx, job, leaseID)
+
+	// The outcome is saved even if ctx was cancelled during the attempt.
+	saveCtx := context.WithoutCancel(ctx)
+	now := q.now()
+	job.UpdatedAt = now
+	job.Error = ""
+	switch {
+	case runErr == nil:
+		job.Status = models.JobCompleted
+		job.FinishedAt = &now
+		if result != nil {
+			if job.Result, err = json.Marshal(result); err != nil {
+				return true, err
+			}
+		}
+	case ctx.Err() != nil:
+		// Shutting down: hand the job back for the next start.
+		job.Status = models.JobQueued
+		job.Attempts--
+		job.RunAt = now
+	default:
+		job.Error = runErr.Error()
+		var permanent permanentError
+		if errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts {
+			job.Status = models.JobDead
+			job.FinishedAt = &now
+		} else {
+			job.Status = models.JobQueued
+			job.RunAt = now.Add(q.retryDelay(job.Attempts))
+		}
+		log.Printf("jobs: %s %s attempt %d failed: %v", job.Type, job.ID, job.Attempts, runErr)
+	}
+	return true, q.store.FinishJobAttempt(saveCtx, job, leaseID)
+}
+
+// attempt runs the job's function while renewing its lease. Progress reported
+// by the function is saved at each renewal and once it returns.
+func (q *JobQueue) attempt(ctx context.Context, job *models.Job, leaseID string) (interface{}, error) {
+	fn, ok := q.handlers[job.Type]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("unknown job type %q", job.Type))
+	}
+	if job.Attempts > job.MaxAttempts {
+		// It kept losing its lease, most likely by crashing the server.
+		return nil, Permanent(errors.New("lease expired too many times"))
+	}
+
+	var mu sync.Mutex
+	var progress json.RawMessage
+	report := func(p interface{}) {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		progress = b
+		mu.Unlock()
+	}
+	latest := func() json.RawMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return progress
+	}
+
+	// Stores fence the job's writes on its lease, so a job that lost it, or
+	// was deleted with its user, can't write anything more.
+	runCtx, cancel := context.WithCancel(store.WithJobLease(ctx, job.ID, leaseID))
+	defer cancel()
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(q.LeaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := q.store.RenewJobLease(runCtx, job.ID, leaseID, q.now().Add(q.LeaseDuration), latest())
+			if errors.Is(err, store.ErrLeaseLost) {
+				log.Printf("jobs: lost lease on %s %s", job.Type, job.ID)
+				cancel()
+				return
+			}
+			if err != nil && runCtx.Err() == nil {
+				log.Printf("jobs: renewing lease on %s %s: %v", job.Type, job.ID, err)
+			}
+		}
+	}()
+
+	result, err := fn(runCtx, job, report)
+	cancel()
+	<-renewed
+	if p := latest(); p != nil {
+		job.Progress = p
+	}
+	return result, err
+}
+
+func (q *JobQueue) retryDelay(attempts int) time.Duration {
+	delay := q.RetryBaseDelay
+	for i := 1; i < attempts && delay < q.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, q.RetryMaxDelay)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const jobSteamID = "76561197960287930"
+
+// jobStores opens each JobStore implementation the queue is tested against.
+var jobStores = map[string]func(t *testing.T) store.JobStore{
+	"memory": func(t *testing.T) store.JobStore { return store.NewMemoryStore() },
+	"sqlite": func(t *testing.T) store.JobStore { return newTestSQLiteStore(t) },
+}
+
+// testClock is a settable clock for JobQueue.now, in whole seconds as the
+// SQL stores keep times.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestQueue(s store.JobStore, fn JobFunc) (*JobQueue, *testClock) {
+	clock := &testClock{now: time.Unix(1700000000, 0)}
+	q := NewJobQueue(s)
+	q.now = clock.Now
+	q.RetryBaseDelay = time.Minute
+	q.RetryMaxDelay = 10 * time.Minute
+	q.Register("test", fn)
+	return q, clock
+}
+
+func mustGetJob(t *testing.T, s store.JobStore, id string) *models.Job {
+	t.Helper()
+	job, err := s.GetJob(context.Background(), id)
+	if err != nil || job == nil {
+		t.Fatalf("GetJob(%s) = %v, %v", id, job, err)
+	}
+	return job
+}
+
+func runJobStoreTests(t *testing.T, fn func(t *testing.T, s store.JobStore)) {
+	for name, open := range jobStores {
+		t.Run(name, func(t *testing.T) { fn(t, open(t)) })
+	}
+}
+
+func TestJobQueueRunsJobOnce(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		runs := 0
+		q, _ := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			runs++
+			report(map[string]int{"done": 1})
+			return map[string]string{"payload": string(job.Payload)}, nil
+		})
+
+		job, err := q.Enqueue(ctx, "test", jobSteamID, map[string]int{"n": 1})
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		again, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+		if err != nil || again.ID != job.ID {
+			t.Fatalf("second Enqueue = %v, %v; want the active job %s", again, err, job.ID)
+		}
+		if _, err := q.Enqueue(ctx, "unknown", jobSteamID, nil); err == nil {
+			t.Error("Enqueue of an unregistered type succeeded")
+		}
+
+		if ran, err := q.runNext(ctx); !ran || err != nil {
+			t.Fatalf("runNext = %v, %v; want the job run", ran, err)
+		}
+		if ran, err := q.runNext(ctx); ran || err != nil {
+			t.Fatalf("runNext with nothing due = %v, %v", ran, err)
+		}
+
+		got := mustGetJob(t, s, job.ID)
+		if runs != 1 || got.Status != models.JobCompleted || got.Attempts != 1 || got.FinishedAt == nil {
+			t.Errorf("after one run: runs %d, job %+v", runs, got)
+		}
+		if string(got.Progress) != `{"done":1}` || string(got.Result) != `{"payload":"{\"n\":1}"}` {
+			t.Errorf("progress %s, result %s", got.Progress, got.Result)
+		}
+		if got.LeaseID != "" {
+			t.Errorf("finished job still leased by %q", got.LeaseID)
+		}
+	})
+}
+
+func TestJobQueueConcurrentEnqueueCreatesOneJob(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		q, _ := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			return nil, nil
+		})
+
+		const callers = 8
+		ids := make([]string, callers)
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				job, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+				if err != nil {
+					t.Errorf("Enqueue: %v", err)
+					return
+				}
+				ids[i] = job.ID
+			}()
+		}
+		wg.Wait()
+
+		for _, id := range ids[1:] {
+			if id != ids[0] {
+				t.Fatalf("concurrent Enqueue returned jobs %v, want one job", ids)
+			}
+		}
+		// The store itself refuses a second active job.
+		dup := *mustGetJob(t, s, ids[0])
+		dup.ID = "duplicate"
+		if err := s.CreateJob(ctx, &dup); !errors.Is(err, store.ErrActiveJobExists) {
+			t.Errorf("CreateJob of a second active job = %v, want ErrActiveJobExists", err)
+		}
+	})
+}
+
+func TestJobQueueRetries(t *testing.T) {
+	fail := errors.New("steam is down")
+	tests := []struct {
+		name        string
+		maxAttempts int
+		err         error
+		// wantDelays are the backoffs before each retry.
+		wantDelays []time.Duration
+		want       models.JobStatus
+	}{
+		{"backs off until dead", 4, fail, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}, models.JobDead},
+		{"permanent error", 4, Permanent(fail), nil, models.JobDead},
+		{"single attempt", 1, fail, nil, models.JobDead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+				ctx := context.Background()
+				runs := 0
+				q, clock := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+					runs++
+					return nil, tt.err
+				})
+				q.MaxAttempts = tt.maxAttempts
+				job, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+				if err != nil {
+					t.Fatalf("Enqueue: %v", err)
+				}
+
+				for i, delay := range tt.wantDelays {
+					if ran, err := q.runNext(ctx); !ran || err != nil {
+						t.Fatalf("attempt %d: runNext = %v, %v", i+1, ran, err)
+					}
+					got := mustGetJob(t, s, job.ID)
+					if got.Status != models.JobQueued || got.Attempts != i+1 || got.Error != fail.Error() {
+						t.Fatalf("after attempt %d: %+v", i+1, got)
+					}
+					if want := clock.Now().Add(delay); !got.RunAt.Equal(want) {
+						t.Errorf("after attempt %d: runs at %v, want %v", i+1, got.RunAt, want)
+					}
+					clock.Advance(delay - time.Second)
+					if ran, _ := q.runNext(ctx); ran {
+						t.Fatalf("attempt %d retried before its backoff", i+1)
+					}
+					clock.Advance(time.Second)
+				}
+
+				if ran, err := q.runNext(ctx); !ran || err != nil {
+					t.Fatalf("last attempt: runNext = %v, %v", ran, err)
+				}
+				got := mustGetJob(t, s, job.ID)
+				if got.Status != tt.want || got.FinishedAt == nil || runs != len(tt.wantDelays)+1 {
+					t.Errorf("after %d runs: %+v", runs, got)
+				}
+				clock.Advance(time.Hour)
+				if ran, _ := q.runNext(ctx); ran {
+					t.Error("dead job ran again")
+				}
+			})
+		})
+	}
+}
+
+func TestJobQueueRenewsLease(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		reported := make(chan struct{})
+		release := make(chan struct{})
+		q, clock := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			report(map[string]int{"step": 1})
+			close(reported)
+			<-release
+			return nil, nil
+		})
+		q.LeaseDuration = 30 * time.Millisecond
+		job, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := q.runNext(ctx)
+			done <- err
+		}()
+		<-reported
+		leasedAt := clock.Now()
+		clock.Advance(time.Hour)
+
+		// A renewal saves progress and pushes the lease past the clock.
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got := mustGetJob(t, s, job.ID)
+			if string(got.Progress) == `{"step":1}` && got.LeasedUntil.After(leasedAt.Add(time.Minute)) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("lease never renewed: %+v", got)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		// The SQL stores keep whole seconds, so look a little before the renewed lease ends.
+		if stolen, err := s.LeaseJob(ctx, leasedAt.Add(59*time.Minute), "thief", clock.Now().Add(time.Minute)); err != nil || stolen != nil {
+			t.Errorf("LeaseJob took over a renewed lease: %v, %v", stolen, err)
+		}
+
+		close(release)
+		if err := <-done; err != nil {
+			t.Fatalf("runNext: %v", err)
+		}
+		if got := mustGetJob(t, s, job.ID); got.Status != models.JobCompleted {
+			t.Errorf("job after run: %+v", got)
+		}
+	})
+}
+
+func TestJobQueueLostLease(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		started := make(chan struct{})
+		q, clock := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			close(started)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("attempt was not cancelled")
+			}
+		})
+		q.LeaseDuration = 30 * time.Millisecond
+		job, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := q.runNext(ctx)
+			done <- err
+		}()
+		<-started
+		// Another worker takes the job over, as if this one had stalled.
+		later := clock.Now().Add(time.Hour)
+		stolen, err := s.LeaseJob(ctx, later, "thief", later.Add(time.Hour))
+		if err != nil || stolen == nil || stolen.ID != job.ID {
+			t.Fatalf("LeaseJob = %v, %v; want the running job", stolen, err)
+		}
+
+		if err := <-done; !errors.Is(err, store.ErrLeaseLost) {
+			t.Fatalf("runNext error = %v, want ErrLeaseLost", err)
+		}
+		got := mustGetJob(t, s, job.ID)
+		if got.Status != models.JobRunning || got.LeaseID != "thief" || got.Attempts != 2 {
+			t.Errorf("job after losing the lease = %+v; want it left to the new holder", got)
+		}
+	})
+}
+
+func TestJobQueuePicksUpAfterRestart(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		noop := func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			return nil, nil
+		}
+		before, clock := newTestQueue(s, noop)
+		queued, err := before.Enqueue(ctx, "test", jobSteamID, nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		crashed, err := before.Enqueue(ctx, "test", "76561197960287931", nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		// The old process leased one job and died without finishing it.
+		now := clock.Now()
+		leased, err := s.LeaseJob(ctx, now, "dead worker", now.Add(time.Minute))
+		if err != nil || leased == nil {
+			t.Fatalf("LeaseJob = %v, %v", leased, err)
+		}
+
+		after, afterClock := newTestQueue(s, noop)
+		afterClock.now = now.Add(30 * time.Second)
+		if ran, err := after.runNext(ctx); !ran || err != nil {
+			t.Fatalf("runNext = %v, %v; want the queued job", ran, err)
+		}
+		if ran, _ := after.runNext(ctx); ran {
+			t.Fatal("runNext took over a lease that had not expired")
+		}
+		afterClock.Advance(time.Minute)
+		if ran, err := after.runNext(ctx); !ran || err != nil {
+			t.Fatalf("runNext = %v, %v; want the expired lease taken over", ran, err)
+		}
+
+		for _, j := range []*models.Job{queued, crashed} {
+			got := mustGetJob(t, s, j.ID)
+			if got.Status != models.JobCompleted {
+				t.Errorf("job %s after restart: %+v", j.ID, got)
+			}
+		}
+		if got := mustGetJob(t, s, leased.ID); got.Attempts != 2 {
+			t.Errorf("taken-over job has %d attempts, want 2", got.Attempts)
+		}
+	})
+}
+
+func TestJobQueueShutdownHandsJobBack(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx, cancel := context.WithCancel(context.Background())
+		q, _ := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			cancel()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		job, err := q.Enqueue(ctx, "test", jobSteamID, nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if _, err := q.runNext(ctx); err != nil {
+			t.Fatalf("runNext: %v", err)
+		}
+		if got := mustGetJob(t, s, job.ID); got.Status != models.JobQueued || got.Attempts != 0 {
+			t.Errorf("interrupted job = %+v; want it queued with no attempt used", got)
+		}
+	})
+}
+
+func TestJobQueuePurgesFinishedJobs(t *testing.T) {
+	runJobStoreTests(t, func(t *testing.T, s store.JobStore) {
+		ctx := context.Background()
+		q, clock := newTestQueue(s, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+			var payload string
+			json.Unmarshal(job.Payload, &payload)
+			if payload == "fail" {
+				return nil, Permanent(errors.New("no"))
+			}
+			return nil, nil
+		})
+		q.Retention = 24 * time.Hour
+
+		var ids []string
+		for i, payload := range []string{"ok", "fail", "ok"} {
+			// Different users, so Enqueue doesn't return the earlier job.
+			steamID := []string{"76561197960287931", "76561197960287932", "76561197960287933"}[i]
+			job, err := q.Enqueue(ctx, "test", steamID, payload)
+			if err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			ids = append(ids, job.ID)
+			if i < 2 {
+				if _, err := q.runNext(ctx); err != nil {
+					t.Fatalf("runNext: %v", err)
+				}
+			}
+		}
+		// The third job is still queued, and a fourth finishes later.
+		clock.Advance(12 * time.Hour)
+		recent, err := q.Enqueue(ctx, "test", jobSteamID, "ok")
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if ran, err := q.runNext(ctx); !ran || err != nil {
+			t.Fatalf("runNext = %v, %v", ran, err)
+		}
+		clock.Advance(13 * time.Hour)
+		if err := s.CreateJob(ctx, &models.Job{ID: "queued-long-ago", Type: "test", SteamID: "76561197960287934", Status: models.JobQueued, RunAt: clock.Now().Add(time.Hour)}); err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+
+		q.purge(ctx)
+
+		for id, kept := range map[string]bool{ids[0]: false, ids[1]: false, ids[2]: true, recent.ID: true, "queued-long-ago": true} {
+			job, err := s.GetJob(ctx, id)
+			if err != nil {
+				t.Fatalf("GetJob: %v", err)
+			}
+			if (job != nil) != kept {
+				t.Errorf("job %s kept = %v, want %v", id, job != nil, kept)
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"time"
+)
+
+// LibraryService persists owned-games lists and records what changed between
+// refreshes: new acquisitions, removals and first plays.
+type LibraryService struct {
+	store store.LibraryStore
+	now   func() time.Time
+}
+
+func NewLibraryService(store store.LibraryStore) *LibraryService {
+	return &LibraryService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Refresh diffs the user's freshly fetched owned games against the stored
+// library and saves the new state. It returns the events it recorded. The
+// diff and save are one store transaction, so concurrent refreshes of the
+// same user can't record the same change twice.
+func (l *LibraryService) Refresh(ctx context.Context, steamID string, games []models.SteamGame) ([]models.LibraryEvent, error) {
+	var events []models.LibraryEvent
+	err := l.store.UpdateLibrary(ctx, steamID, func(previous map[int]*models.LibraryEntry) ([]models.LibraryEntry, []models.LibraryEvent) {
+		var entries []models.LibraryEntry
+		entries, events = diffLibrary(previous, games, l.now())
+		return entries, events
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Changes returns the events recorded after since. It only reads what the
+// scheduler's refreshes have stored.
+func (l *LibraryService) Changes(ctx context.Context, steamID string, since time.Time) ([]models.LibraryEvent, error) {
+	return l.store.ListLibraryEvents(ctx, steamID, since)
+}
+
+// diffLibrary returns the entries to save and the events between previous and
+// the freshly fetched games. The first refresh only records a baseline.
+func diffLibrary(previous map[int]*models.LibraryEntry, games []models.SteamGame, now time.Time) ([]models.LibraryEntry, []models.LibraryEvent) {
+	active := 0
+	for _, e := range previous {
+		if !e.Removed {
+			active++
+		}
+	}
+	// Steam returns an empty list, not an error, when game details are private.
+	// Treating that as every game being removed would flood the feed.
+	if len(games) == 0 && active > 0 {
+		return nil, nil
+	}
+
+	baseline := len(previous) == 0
+	var entries []models.LibraryEntry
+	var events []models.LibraryEvent
+	event := func(appID int, name string, typ models.LibraryEventType) {
+		if !baseline {
+			events = append(events, models.LibraryEvent{AppID: appID, Name: name, Type: typ, At: now})
+		}
+	}
+
+	seen := make(map[int]bool, len(games))
+	for _, g := range games {
+		seen[g.AppID] = true
+		entry := models.LibraryEntry{
+			AppID:           g.AppID,
+			Name:            g.Name,
+			PlaytimeForever: g.PlaytimeForever,
+			FirstSeen:       now,
+			LastSeen:        now,
+		}
+
+		old, ok := previous[g.AppID]
+		switch {
+		case !ok || old.Removed:
+			event(g.AppID, g.Name, models.LibraryEventAcquired)
+			if g.PlaytimeForever > 0 && (!ok || old.PlaytimeForever == 0) {
+				event(g.AppID, g.Name, models.LibraryEventFirstPlayed)
+			}
+		case old.PlaytimeForever == 0 && g.PlaytimeForever > 0:
+			event(g.AppID, g.Name, models.LibraryEventFirstPlayed)
+		}
+		if ok {
+			entry.FirstSeen = old.FirstSeen
+		}
+		entries = append(entries, entry)
+	}
+
+	for appID, old := range previous {
+		if seen[appID] || old.Removed {
+			continue
+		}
+		removed := *old
+		removed.Removed = true
+		entries = append(entries, removed)
+		event(appID, old.Name, models.LibraryEventRemoved)
+	}
+	return entries, events
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLibraryConcurrentRefreshesRecordEachChangeOnce(t *testing.T) {
+	ctx := context.Background()
+	const steamID = "76561197960287930"
+
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer db.Close()
+	l := NewLibraryService(db)
+	l.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	if _, err := l.Refresh(ctx, steamID, []models.SteamGame{{AppID: 440, Name: "Team Fortress 2"}}); err != nil {
+		t.Fatalf("baseline Refresh: %v", err)
+	}
+
+	games := []models.SteamGame{
+		{AppID: 440, Name: "Team Fortress 2"},
+		{AppID: 570, Name: "Dota 2", PlaytimeForever: 30},
+	}
+	const refreshes = 8
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < refreshes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := l.Refresh(ctx, steamID, games); err != nil {
+				t.Errorf("Refresh: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	events, err := l.Changes(ctx, steamID, time.Time{})
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	counts := make(map[models.LibraryEventType]int)
+	for _, ev := range events {
+		if ev.AppID != 570 {
+			t.Errorf("unexpected event %+v", ev)
+		}
+		counts[ev.Type]++
+	}
+	if counts[models.LibraryEventAcquired] != 1 || counts[models.LibraryEventFirstPlayed] != 1 || len(events) != 2 {
+		t.Errorf("concurrent refreshes recorded %v, want one acquired and one first_played", counts)
+	}
+}
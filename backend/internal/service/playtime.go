@@ -0,0 +1,213 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Granularity is the bucket size of a playtime trend.
+type Granularity string
+
+const (
+	Daily   Granularity = "day"
+	Weekly  Granularity = "week"
+	Monthly Granularity = "month"
+)
+
+// ParseGranularity accepts "day", "week" or "month".
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(s); g {
+	case Daily, Weekly, Monthly:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown granularity %q", s)
+}
+
+// Start returns the start of the bucket containing t, in UTC. Weeks start on Monday.
+func (g Granularity) Start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch g {
+	case Weekly:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the start of the bucket after the one starting at start.
+func (g Granularity) Next(start time.Time) time.Time {
+	switch g {
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// PlaytimeService snapshots a user's cumulative playtime and stores the
+// per-day differences, which trends are built from. Snapshots are taken by
+// the Scheduler.
+type PlaytimeService struct {
+	store store.PlaytimeStore
+	// CompactAfter is the age after which daily deltas are merged into months.
+	CompactAfter time.Duration
+	now          func() time.Time
+}
+
+func NewPlaytimeService(store store.PlaytimeStore) *PlaytimeService {
+	return &PlaytimeService{
+		store:        store,
+		CompactAfter: 90 * 24 * time.Hour,
+		now:          time.Now,
+	}
+}
+
+// Snapshot records playtime gained since the previous snapshot against
+// today's date, given the user's freshly fetched owned games. The first
+// snapshot of a game only sets its baseline.
+func (p *PlaytimeService) Snapshot(ctx context.Context, steamID string, games []models.SteamGame) error {
+	previous, err := p.store.GetPlaytimeTotals(ctx, steamID)
+	if err != nil {
+		return err
+	}
+
+	day := Daily.Start(p.now())
+	totals := make(map[int]int)
+	var deltas []models.PlaytimeDelta
+	for _, g := range games {
+		old, ok := previous[g.AppID]
+		if ok && old == g.PlaytimeForever {
+			continue
+		}
+		totals[g.AppID] = g.PlaytimeForever
+		// Steam occasionally corrects playtime downwards; that is not negative play.
+		if ok && g.PlaytimeForever > old {
+			deltas = append(deltas, models.PlaytimeDelta{AppID: g.AppID, Day: day, Minutes: g.PlaytimeForever - old})
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	return p.store.RecordPlaytime(ctx, steamID, totals, deltas)
+}
+
+// Compact merges the user's daily deltas older than CompactAfter into one
+// delta per game and month. Only whole months are compacted.
+func (p *PlaytimeService) Compact(ctx context.Context, steamID string) error {
+	cutoff := Monthly.Start(p.now().Add(-p.CompactAfter))
+	old, err := p.store.GetPlaytimeDeltas(ctx, steamID, time.Unix(0, 0), cutoff)
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		appID int
+		month time.Time
+	}
+	merged := make(map[key]int)
+	alreadyCompact := true
+	for _, d := range old {
+		month := Monthly.Start(d.Day)
+		if !d.Day.Equal(month) {
+			alreadyCompact = false
+		}
+		k := key{d.AppID, month}
+		if _, ok := merged[k]; ok {
+			alreadyCompact = false
+		}
+		merged[k] += d.Minutes
+	}
+	if alreadyCompact {
+		return nil
+	}
+
+	deltas := make([]models.PlaytimeDelta, 0, len(merged))
+	for k, minutes := range merged {
+		deltas = append(deltas, models.PlaytimeDelta{AppID: k.appID, Day: k.month, Minutes: minutes})
+	}
+	return p.store.ReplacePlaytimeDeltas(ctx, steamID, cutoff, deltas)
+}
+
+// Trend returns total playtime per bucket in [from, to), including empty
+// buckets. If appID is non-zero only that game is counted. Months older than
+// CompactAfter only have a total per game, which is spread evenly over the
+// month's days.
+func (p *PlaytimeService) Trend(ctx context.Context, steamID string, appID int, g Granularity, from, to time.Time) ([]models.PlaytimeBucket, error) {
+	from = g.Start(from)
+	cutoff := Monthly.Start(p.now().Add(-p.CompactAfter))
+	queryFrom := from
+	if from.Before(cutoff) {
+		queryFrom = Monthly.Start(from)
+	}
+	deltas, err := p.store.GetPlaytimeDeltas(ctx, steamID, queryFrom, to)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []models.PlaytimeBucket{}
+	index := make(map[time.Time]int)
+	for start := from; start.Before(to); start = g.Next(start) {
+		index[start] = len(buckets)
+		buckets = append(buckets, models.PlaytimeBucket{Start: start})
+	}
+	for _, d := range deltas {
+		if appID != 0 && d.AppID != appID {
+			continue
+		}
+		if !d.Day.Before(cutoff) || !d.Day.Equal(Monthly.Start(d.Day)) {
+			if i, ok := index[g.Start(d.Day)]; ok {
+				buckets[i].Minutes += d.Minutes
+			}
+			continue
+		}
+		// A compacted month: day n of days gets its share, with the
+		// remainder spread so the shares add up to the total.
+		days := d.Day.AddDate(0, 1, -1).Day()
+		for n := 0; n < days; n++ {
+			day := d.Day.AddDate(0, 0, n)
+			if i, ok := index[g.Start(day)]; ok {
+				buckets[i].Minutes += d.Minutes*(n+1)/days - d.Minutes*n/days
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// TopGames returns playtime per game in [from, to), most played first. Days
+// older than CompactAfter have been merged into months, so there from is
+// rounded down to the start of its month.
+func (p *PlaytimeService) TopGames(ctx context.Context, steamID string, from, to time.Time) ([]models.PlaytimeTotal, error) {
+	from = Daily.Start(from)
+	if from.Before(Monthly.Start(p.now().Add(-p.CompactAfter))) {
+		from = Monthly.Start(from)
+	}
+	deltas, err := p.store.GetPlaytimeDeltas(ctx, steamID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := make(map[int]int)
+	for _, d := range deltas {
+		minutes[d.AppID] += d.Minutes
+	}
+	totals := make([]models.PlaytimeTotal, 0, len(minutes))
+	for appID, m := range minutes {
+		totals = append(totals, models.PlaytimeTotal{AppID: appID, Minutes: m})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Minutes != totals[j].Minutes {
+			return totals[i].Minutes > totals[j].Minutes
+		}
+		return totals[i].AppID < totals[j].AppID
+	})
+	return totals, nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+)
+
+const playtimeSteamID = "76561197960287930"
+
+func newTestPlaytimeService(t *testing.T, now *time.Time) *PlaytimeService {
+	t.Helper()
+	p := NewPlaytimeService(newTestSQLiteStore(t))
+	p.now = func() time.Time { return *now }
+	return p
+}
+
+func ownedGames(playtimes map[int]int) []models.SteamGame {
+	games := make([]models.SteamGame, 0, len(playtimes))
+	for appID, minutes := range playtimes {
+		games = append(games, models.SteamGame{AppID: appID, PlaytimeForever: minutes})
+	}
+	return games
+}
+
+func allDeltas(t *testing.T, p *PlaytimeService) []models.PlaytimeDelta {
+	t.Helper()
+	deltas, err := p.store.GetPlaytimeDeltas(context.Background(), playtimeSteamID, time.Unix(0, 0), time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetPlaytimeDeltas: %v", err)
+	}
+	return deltas
+}
+
+func TestPlaytimeSnapshot(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 18, 30, 0, 0, time.UTC)
+	p := newTestPlaytimeService(t, &now)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	steps := []struct {
+		name   string
+		games  map[int]int
+		totals map[int]int
+		deltas []models.PlaytimeDelta
+	}{
+		{
+			name:   "first snapshot is the baseline",
+			games:  map[int]int{440: 600, 570: 0},
+			totals: map[int]int{440: 600, 570: 0},
+		},
+		{
+			name:   "gains are recorded against today",
+			games:  map[int]int{440: 645, 570: 0},
+			totals: map[int]int{440: 645, 570: 0},
+			deltas: []models.PlaytimeDelta{{AppID: 440, Day: day(12), Minutes: 45}},
+		},
+		{
+			name:   "unchanged playtime stores no delta",
+			games:  map[int]int{440: 645, 570: 0},
+			totals: map[int]int{440: 645, 570: 0},
+			deltas: []models.PlaytimeDelta{{AppID: 440, Day: day(12), Minutes: 45}},
+		},
+		{
+			name:   "a reset moves the baseline without a negative delta",
+			games:  map[int]int{440: 100, 570: 0},
+			totals: map[int]int{440: 100, 570: 0},
+			deltas: []models.PlaytimeDelta{{AppID: 440, Day: day(12), Minutes: 45}},
+		},
+		{
+			name:   "play after a reset counts from the new baseline",
+			games:  map[int]int{440: 130, 570: 0, 730: 20},
+			totals: map[int]int{440: 130, 570: 0, 730: 20},
+			deltas: []models.PlaytimeDelta{
+				{AppID: 440, Day: day(12), Minutes: 45},
+				{AppID: 440, Day: day(15), Minutes: 30},
+			},
+		},
+	}
+	for _, step := range steps {
+		now = now.AddDate(0, 0, 1)
+		if err := p.Snapshot(ctx, playtimeSteamID, ownedGames(step.games)); err != nil {
+			t.Fatalf("%s: Snapshot: %v", step.name, err)
+		}
+
+		totals, err := p.store.GetPlaytimeTotals(ctx, playtimeSteamID)
+		if err != nil {
+			t.Fatalf("%s: GetPlaytimeTotals: %v", step.name, err)
+		}
+		if len(totals) != len(step.totals) {
+			t.Errorf("%s: totals = %v, want %v", step.name, totals, step.totals)
+		}
+		for appID, want := range step.totals {
+			if totals[appID] != want {
+				t.Errorf("%s: total for %d = %d, want %d", step.name, appID, totals[appID], want)
+			}
+		}
+
+		deltas := allDeltas(t, p)
+		if len(deltas) != len(step.deltas) {
+			t.Fatalf("%s: deltas = %+v, want %+v", step.name, deltas, step.deltas)
+		}
+		for i, want := range step.deltas {
+			if d := deltas[i]; d.AppID != want.AppID || !d.Day.Equal(want.Day) || d.Minutes != want.Minutes {
+				t.Errorf("%s: delta %d = %+v, want %+v", step.name, i, d, want)
+			}
+		}
+	}
+}
+
+func TestPlaytimeCompactKeepsCurrentMonth(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	p := newTestPlaytimeService(t, &now)
+	p.CompactAfter = 0
+	date := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+
+	daily := map[time.Time]map[int]int{
+		date(1, 5):  {440: 10},
+		date(1, 20): {440: 20, 570: 5},
+		date(2, 1):  {440: 7},
+		date(2, 29): {570: 8},
+		date(3, 1):  {440: 30},
+		date(3, 19): {440: 40},
+	}
+	for day, minutes := range daily {
+		var deltas []models.PlaytimeDelta
+		for appID, m := range minutes {
+			deltas = append(deltas, models.PlaytimeDelta{AppID: appID, Day: day, Minutes: m})
+		}
+		if err := p.store.RecordPlaytime(ctx, playtimeSteamID, nil, deltas); err != nil {
+			t.Fatalf("RecordPlaytime: %v", err)
+		}
+	}
+
+	want := []models.PlaytimeDelta{
+		{AppID: 440, Day: date(1, 1), Minutes: 30},
+		{AppID: 570, Day: date(1, 1), Minutes: 5},
+		{AppID: 440, Day: date(2, 1), Minutes: 7},
+		{AppID: 570, Day: date(2, 1), Minutes: 8},
+		{AppID: 440, Day: date(3, 1), Minutes: 30},
+		{AppID: 440, Day: date(3, 19), Minutes: 40},
+	}
+	// A second run finds nothing left to merge.
+	for run := 1; run <= 2; run++ {
+		if err := p.Compact(ctx, playtimeSteamID); err != nil {
+			t.Fatalf("Compact run %d: %v", run, err)
+		}
+		got := allDeltas(t, p)
+		byKey := make(map[[2]int64]int)
+		for _, d := range got {
+			byKey[[2]int64{int64(d.AppID), d.Day.Unix()}] = d.Minutes
+		}
+		if len(got) != len(want) {
+			t.Errorf("run %d: deltas = %+v, want %+v", run, got, want)
+		}
+		for _, w := range want {
+			if m := byKey[[2]int64{int64(w.AppID), w.Day.Unix()}]; m != w.Minutes {
+				t.Errorf("run %d: app %d on %s = %d minutes, want %d", run, w.AppID, w.Day.Format("2006-01-02"), m, w.Minutes)
+			}
+		}
+	}
+}
+
+func TestPlaytimeTopGamesCountsCompactedMonths(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	p := newTestPlaytimeService(t, &now)
+	date := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+
+	deltas := []models.PlaytimeDelta{
+		{AppID: 440, Day: date(1, 10), Minutes: 50},
+		{AppID: 570, Day: date(1, 25), Minutes: 20},
+		{AppID: 570, Day: date(6, 3), Minutes: 90},
+		{AppID: 730, Day: date(6, 18), Minutes: 5},
+	}
+	if err := p.store.RecordPlaytime(ctx, playtimeSteamID, nil, deltas); err != nil {
+		t.Fatalf("RecordPlaytime: %v", err)
+	}
+	// January is older than CompactAfter, so its days are merged into the 1st.
+	if err := p.Compact(ctx, playtimeSteamID); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []models.PlaytimeTotal
+	}{
+		{
+			name: "range starting inside a compacted month",
+			from: date(1, 15),
+			to:   date(7, 1),
+			want: []models.PlaytimeTotal{{AppID: 570, Minutes: 110}, {AppID: 440, Minutes: 50}, {AppID: 730, Minutes: 5}},
+		},
+		{
+			name: "recent days stay daily",
+			from: date(6, 10),
+			to:   date(7, 1),
+			want: []models.PlaytimeTotal{{AppID: 730, Minutes: 5}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := p.TopGames(ctx, playtimeSteamID, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("%s: TopGames: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: TopGames = %+v, want %+v", tt.name, got, tt.want)
+		}
+		for i, want := range tt.want {
+			if got[i] != want {
+				t.Errorf("%s: TopGames[%d] = %+v, want %+v", tt.name, i, got[i], want)
+			}
+		}
+	}
+}
+
+func TestPlaytimeTrendSpreadsCompactedMonths(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	p := newTestPlaytimeService(t, &now)
+	date := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+
+	deltas := []models.PlaytimeDelta{
+		{AppID: 440, Day: date(1, 10), Minutes: 40},
+		{AppID: 440, Day: date(1, 20), Minutes: 22},
+		{AppID: 570, Day: date(1, 25), Minutes: 31},
+		{AppID: 440, Day: date(6, 18), Minutes: 5},
+	}
+	if err := p.store.RecordPlaytime(ctx, playtimeSteamID, nil, deltas); err != nil {
+		t.Fatalf("RecordPlaytime: %v", err)
+	}
+	// January is merged into 62 minutes of 440 and 31 of 570 on the 1st, so
+	// each of its 31 days gets 2 and 1; the last week holds 3 of them.
+	if err := p.Compact(ctx, playtimeSteamID); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		appID int
+		g     Granularity
+		from  time.Time
+		to    time.Time
+		want  []int
+	}{
+		{name: "weekly from mid-month", g: Weekly, from: date(1, 17), to: date(2, 5), want: []int{21, 21, 9}},
+		{name: "weekly for one game", appID: 440, g: Weekly, from: date(1, 17), to: date(2, 5), want: []int{14, 14, 6}},
+		{name: "daily around the 1st", g: Daily, from: date(1, 1), to: date(1, 3), want: []int{3, 3}},
+		{name: "monthly", g: Monthly, from: date(1, 15), to: date(3, 1), want: []int{93, 0}},
+		{name: "recent days stay daily", g: Daily, from: date(6, 17), to: date(6, 20), want: []int{0, 5, 0}},
+	}
+	for _, tt := range tests {
+		got, err := p.Trend(ctx, playtimeSteamID, tt.appID, tt.g, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("%s: Trend: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: Trend = %+v, want %d buckets", tt.name, got, len(tt.want))
+		}
+		for i, want := range tt.want {
+			if got[i].Minutes != want {
+				t.Errorf("%s: bucket %s = %d minutes, want %d", tt.name, got[i].Start.Format("2006-01-02"), got[i].Minutes, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		// Shifting this far overflows; the delay still stays at MaxDelay.
+		{70, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 200; i++ {
+			d := p.backoff(tt.attempt, 0)
+			// Equal jitter keeps at least half the ceiling.
+			if d < tt.ceiling/2 || d > tt.ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+func TestBackoffJitterSpreads(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Second}
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		seen[p.backoff(0, 0)] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("50 backoffs produced %d distinct delays, want jitter", len(seen))
+	}
+}
+
+func TestBackoffRetryAfterFloor(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 5 * time.Second}
+	for i := 0; i < 50; i++ {
+		if d := p.backoff(0, 3*time.Second); d != 3*time.Second {
+			t.Fatalf("backoff with Retry-After 3s = %v, want 3s", d)
+		}
+	}
+	// A Retry-After shorter than the backoff does not shorten it.
+	if d := p.backoff(8, time.Millisecond); d < 1280*time.Millisecond {
+		t.Errorf("backoff(8) with Retry-After 1ms = %v, want at least 1.28s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"zero seconds", "0", 0},
+		{"negative seconds", "-5", 0},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"http date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"garbage", "soon", 0},
+		{"fractional seconds", "1.5", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("%s: parseRetryAfter(%q) = %v, want %v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	// The full burst is available straight away.
+	for i := 0; i < 3; i++ {
+		if d := l.Reserve(); d != 0 {
+			t.Fatalf("reservation %d within burst waits %v, want 0", i, d)
+		}
+	}
+	// At 2 tokens a second, the next two are 500ms apart.
+	if d := l.Reserve(); d != 500*time.Millisecond {
+		t.Errorf("first reservation past burst waits %v, want 500ms", d)
+	}
+	if d := l.Reserve(); d != time.Second {
+		t.Errorf("second reservation past burst waits %v, want 1s", d)
+	}
+
+	// After a long idle spell the bucket refills to burst and no further.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if d := l.Reserve(); d != 0 {
+			t.Fatalf("reservation %d after idle waits %v, want 0", i, d)
+		}
+	}
+	if d := l.Reserve(); d != 500*time.Millisecond {
+		t.Errorf("reservation past refilled burst waits %v, want 500ms", d)
+	}
+}
+
+func TestRateLimiterPartialRefill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	if d := l.Reserve(); d != 0 {
+		t.Fatalf("first reservation waits %v, want 0", d)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if d := l.Reserve(); d != 750*time.Millisecond {
+		t.Errorf("reservation 250ms later waits %v, want 750ms", d)
+	}
+}
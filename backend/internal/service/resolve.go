@@ -0,0 +1,94 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/steamid"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidSteamID is returned for input that is neither a SteamID, a profile link nor a vanity name.
+	ErrInvalidSteamID = steamid.ErrInvalid
+	// ErrProfileNotFound is returned when a vanity name does not belong to any profile.
+	ErrProfileNotFound = errors.New("steam profile not found")
+)
+
+// ResolveSteamID normalizes user input to a SteamID64. It accepts any format
+// steamid.Parse understands, a steamcommunity.com profile link, or a bare
+// vanity name, which is looked up with ResolveVanityURL. A bare number is
+// always taken as a SteamID64 or account ID, never as a vanity name.
+func (s *SteamClient) ResolveSteamID(ctx context.Context, input string) (string, error) {
+	input = strings.TrimSpace(input)
+
+	if IsProfileURL(input) {
+		kind, value, err := parseProfileURL(input)
+		if err != nil {
+			return "", err
+		}
+		if kind == "id" {
+			return s.ResolveVanityURL(ctx, value)
+		}
+		id, err := steamid.Parse(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q", ErrInvalidSteamID, input)
+		}
+		return id.String(), nil
+	}
+
+	if id, err := steamid.Parse(input); err == nil {
+		return id.String(), nil
+	}
+	if steamid.IsVanityName(input) {
+		return s.ResolveVanityURL(ctx, input)
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidSteamID, input)
+}
+
+// ResolveVanityURL looks up the SteamID64 behind a custom profile name.
+func (s *SteamClient) ResolveVanityURL(ctx context.Context, vanity string) (string, error) {
+	if !steamid.IsVanityName(vanity) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSteamID, vanity)
+	}
+
+	q := url.Values{}
+	q.Set("vanityurl", strings.ToLower(vanity))
+
+	var resp models.ResolveVanityURLResponse
+	if err := s.get(ctx, "/ISteamUser/ResolveVanityURL/v0001/", q, &resp); err != nil {
+		return "", err
+	}
+	if resp.Response.Success != 1 || resp.Response.SteamID == "" {
+		return "", fmt.Errorf("%w: %q", ErrProfileNotFound, vanity)
+	}
+	return resp.Response.SteamID, nil
+}
+
+// IsProfileURL reports whether input looks like a steamcommunity.com profile link.
+func IsProfileURL(input string) bool {
+	return strings.Contains(input, "steamcommunity.com/")
+}
+
+// parseProfileURL splits https://steamcommunity.com/id/{name} or /profiles/{id}
+// into its kind ("id" or "profiles") and value.
+func parseProfileURL(raw string) (string, string, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidSteamID, raw)
+	}
+	if host := u.Hostname(); host != "steamcommunity.com" && !strings.HasSuffix(host, ".steamcommunity.com") {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidSteamID, raw)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "id" && parts[0] != "profiles") || parts[1] == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidSteamID, raw)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResolveSteamID(t *testing.T) {
+	const gaben = "76561197960287930"
+	var lookups atomic.Int64
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "ResolveVanityURL") {
+			http.NotFound(w, r)
+			return
+		}
+		lookups.Add(1)
+		if r.URL.Query().Get("vanityurl") == "gaben" {
+			fmt.Fprintf(w, `{"response": {"steamid": %q, "success": 1}}`, gaben)
+			return
+		}
+		// Steam's answer for a name no profile uses.
+		w.Write([]byte(`{"response": {"success": 42, "message": "No match"}}`))
+	}))
+	defer steam.Close()
+	client := NewSteamClient("key", WithBaseURL(steam.URL))
+
+	tests := []struct {
+		input   string
+		want    string
+		err     error
+		lookups int64
+	}{
+		{input: gaben, want: gaben},
+		{input: " [U:1:22202] ", want: gaben},
+		{input: "https://steamcommunity.com/profiles/" + gaben, want: gaben},
+		{input: "https://steamcommunity.com/profiles/" + gaben + "/", want: gaben},
+		{input: "steamcommunity.com/profiles/" + gaben + "/?l=english", want: gaben},
+		{input: "http://www.steamcommunity.com/profiles/" + gaben + "#games", want: gaben},
+		{input: "https://steamcommunity.com/id/gaben", want: gaben, lookups: 1},
+		{input: "https://steamcommunity.com/id/GabeN/", want: gaben, lookups: 1},
+		{input: "steamcommunity.com/id/gaben/?tab=all", want: gaben, lookups: 1},
+		{input: "https://steamcommunity.com/id/gaben/games/", want: gaben, lookups: 1},
+		{input: "gaben", want: gaben, lookups: 1},
+		{input: "https://steamcommunity.com/id/nobody-here", err: ErrProfileNotFound, lookups: 1},
+		{input: "nobody-here", err: ErrProfileNotFound, lookups: 1},
+		{input: "https://steamcommunity.com/profiles/gaben", err: ErrInvalidSteamID},
+		{input: "https://steamcommunity.com/groups/valve", err: ErrInvalidSteamID},
+		{input: "https://steamcommunity.com/id/", err: ErrInvalidSteamID},
+		{input: "https://example.com/steamcommunity.com/id/gaben", err: ErrInvalidSteamID},
+		{input: "not a name", err: ErrInvalidSteamID},
+	}
+	for _, tt := range tests {
+		before := lookups.Load()
+		got, err := client.ResolveSteamID(context.Background(), tt.input)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ResolveSteamID(%q) = %q, %v; want %v", tt.input, got, err, tt.err)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("ResolveSteamID(%q) = %q, %v; want %s", tt.input, got, err, tt.want)
+		}
+		if n := lookups.Load() - before; n != tt.lookups {
+			t.Errorf("ResolveSteamID(%q) made %d vanity lookups, want %d", tt.input, n, tt.lookups)
+		}
+	}
+}
+
+func TestIsProfileURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://steamcommunity.com/id/gaben":                      true,
+		"https://steamcommunity.com/id/gaben/":                     true,
+		"steamcommunity.com/profiles/76561197960287930?l=german":   true,
+		"http://www.steamcommunity.com/profiles/76561197960287930": true,
+		"gaben":                                  false,
+		"76561197960287930":                      false,
+		"STEAM_0:0:11101":                        false,
+		"https://store.steampowered.com/app/440": false,
+	}
+	for input, want := range tests {
+		if got := IsProfileURL(input); got != want {
+			t.Errorf("IsProfileURL(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Scheduler periodically refreshes every registered user: profile, owned
+// games (library changes), recently played games (merged into the playtime
+// snapshot) and, through a queued job, achievement progress. Steam calls
+// bypass the cache, and owned games are fetched once per user.
+type Scheduler struct {
+	store       store.Store
+	data        *DataService
+	steamClient *SteamClient
+	library     *LibraryService
+	playtime    *PlaytimeService
+	jobs        *JobQueue
+
+	// Interval is the time between the start of two passes over all users.
+	Interval time.Duration
+	// Jitter is the maximum random delay added to each interval, so that
+	// several instances don't hit Steam in lockstep.
+	Jitter time.Duration
+	// Concurrency bounds how many users are refreshed at once.
+	Concurrency int
+	// PrivateBackoff is how long a private profile is skipped for. It doubles
+	// on each further private result, up to MaxBackoff.
+	PrivateBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	mu       sync.Mutex
+	paused   bool
+	running  bool
+	lastRun  *time.Time
+	nextRun  *time.Time
+	backoffs map[string]userBackoff
+	now      func() time.Time
+}
+
+type userBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// SchedulerStatus is a snapshot of the scheduler's state.
+type SchedulerStatus struct {
+	Paused    bool       `json:"paused"`
+	Running   bool       `json:"running"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	NextRun   *time.Time `json:"nextRun,omitempty"`
+	BackedOff int        `json:"backedOff"`
+}
+
+// NewScheduler creates a scheduler that calls Steam through steamClient
+// without its cache.
+func NewScheduler(store store.Store, steamClient *SteamClient, library *LibraryService, playtime *PlaytimeService, jobs *JobQueue) *Scheduler {
+	uncached := steamClient.WithoutCache()
+	return &Scheduler{
+		store:          store,
+		data:           NewDataService(store, uncached),
+		steamClient:    uncached,
+		library:        library,
+		playtime:       playtime,
+		jobs:           jobs,
+		Interval:       6 * time.Hour,
+		Jitter:         10 * time.Minute,
+		Concurrency:    2,
+		PrivateBackoff: 24 * time.Hour,
+		MaxBackoff:     14 * 24 * time.Hour,
+		backoffs:       make(map[string]userBackoff),
+		now:            time.Now,
+	}
+}
+
+// Pause stops the scheduler from starting new passes or new users. Users
+// already being refreshed finish.
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+func (s *Scheduler) Status() SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SchedulerStatus{Paused: s.paused, Running: s.running, LastRun: s.lastRun, NextRun: s.nextRun}
+	now := s.now()
+	for _, b := range s.backoffs {
+		if now.Before(b.until) {
+			status.BackedOff++
+		}
+	}
+	return status
+}
+
+// Run refreshes all users every Interval (plus jitter) until ctx is
+// cancelled. It returns once the pass in progress has stopped.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		if !s.isPaused() {
+			s.runPass(ctx)
+		}
+
+		wait := s.Interval
+		if s.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(s.Jitter)))
+		}
+		next := s.now().Add(wait)
+		s.mu.Lock()
+		s.nextRun = &next
+		s.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+func (s *Scheduler) runPass(ctx context.Context) {
+	ids, err := s.store.ListUserIDs(ctx)
+	if err != nil {
+		log.Printf("scheduler: failed to list users: %v", err)
+		return
+	}
+
+	started := s.now()
+	s.mu.Lock()
+	s.running = true
+	s.lastRun = &started
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for range max(s.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				s.refreshUser(ctx, id)
+			}
+		}()
+	}
+feed:
+	for _, id := range ids {
+		if s.isPaused() {
+			break
+		}
+		if s.backingOff(id) {
+			continue
+		}
+		select {
+		case work <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+}
+
+// refreshUser runs every refresh for one user. Failures are logged and the
+// next step still runs, except for private profiles, which back off. Users
+// erased since the pass listed them are skipped, and the store refuses the
+// writes of a refresh that is under way when they are erased.
+func (s *Scheduler) refreshUser(ctx context.Context, steamID string) {
+	if existing, err := s.store.GetUser(ctx, steamID); err != nil || existing == nil {
+		if err != nil {
+			log.Printf("scheduler: failed to load %s: %v", steamID, err)
+		}
+		return
+	}
+	ctx = store.WithRegisteredUser(ctx)
+
+	user, err := s.data.RegisterOrUpdateUser(ctx, steamID)
+	if err == nil && user != nil && user.CommunityVisibilityState != 3 {
+		err = fmt.Errorf("profile %s: %w", steamID, ErrPrivateProfile)
+	}
+	if s.check(ctx, steamID, "profile", err) {
+		return
+	}
+
+	games, err := s.steamClient.GetOwnedGames(ctx, steamID)
+	if s.check(ctx, steamID, "owned games", err) {
+		return
+	}
+	if err == nil {
+		_, err = s.library.Refresh(ctx, steamID, games)
+		if s.check(ctx, steamID, "library", err) {
+			return
+		}
+		recent, err := s.steamClient.GetRecentlyPlayedGames(ctx, steamID)
+		if s.check(ctx, steamID, "recently played", err) {
+			return
+		}
+		if s.check(ctx, steamID, "playtime snapshot", s.playtime.Snapshot(ctx, steamID, mergeRecentlyPlayed(games, recent))) {
+			return
+		}
+	}
+	if s.check(ctx, steamID, "playtime compaction", s.playtime.Compact(ctx, steamID)) {
+		return
+	}
+	_, err = s.jobs.Enqueue(ctx, AchievementSyncJob, steamID, nil)
+	if s.check(ctx, steamID, "achievements", err) {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.backoffs, steamID)
+	s.mu.Unlock()
+}
+
+// mergeRecentlyPlayed returns the owned games plus recently played games
+// missing from them, such as family-shared games, so their playtime is
+// snapshotted too. Where both lists have a game the higher total wins, since
+// either can lag behind the other. owned is not modified.
+func mergeRecentlyPlayed(owned, recent []models.SteamGame) []models.SteamGame {
+	if len(recent) == 0 {
+		return owned
+	}
+	index := make(map[int]int, len(owned))
+	merged := append([]models.SteamGame(nil), owned...)
+	for i, g := range merged {
+		index[g.AppID] = i
+	}
+	for _, g := range recent {
+		i, ok := index[g.AppID]
+		if !ok {
+			index[g.AppID] = len(merged)
+			merged = append(merged, g)
+			continue
+		}
+		if g.PlaytimeForever > merged[i].PlaytimeForever {
+			merged[i].PlaytimeForever = g.PlaytimeForever
+		}
+	}
+	return merged
+}
+
+// check logs err and reports whether the user's refresh should stop: when
+// ctx is done, the user has been erased, or the profile is private, in which
+// case the user backs off.
+func (s *Scheduler) check(ctx context.Context, steamID, step string, err error) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, store.ErrUnknownUser) {
+		return true
+	}
+	if errors.Is(err, ErrPrivateProfile) {
+		s.backOff(steamID)
+		return true
+	}
+	log.Printf("scheduler: %s refresh for %s failed: %v", step, steamID, err)
+	return false
+}
+
+func (s *Scheduler) backOff(steamID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.backoffs[steamID]
+	b.failures++
+	delay := s.PrivateBackoff
+	for i := 1; i < b.failures && delay < s.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, s.MaxBackoff)
+	b.until = s.now().Add(delay)
+	s.backoffs[steamID] = b
+	log.Printf("scheduler: %s is private, skipping for %s", steamID, delay)
+}
+
+func (s *Scheduler) backingOff(steamID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.backoffs[steamID]
+	return ok && s.now().Before(b.until)
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const schedulerSteamID = "76561197960287930"
+
+// steamStub answers the calls a scheduler pass makes and counts them by path.
+type steamStub struct {
+	mu    sync.Mutex
+	calls map[string]int
+	// before, if set, runs before each response.
+	before func(path string)
+	// private makes the profile report itself as private.
+	private bool
+	// recent is the recently played games response; none if empty.
+	recent string
+	// name is the profile's persona name; "gabe" if empty.
+	name string
+}
+
+func (s *steamStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls[r.URL.Path]++
+	before := s.before
+	s.mu.Unlock()
+	if before != nil {
+		before(r.URL.Path)
+	}
+
+	switch {
+	case strings.Contains(r.URL.Path, "GetPlayerSummaries"):
+		visibility := 3
+		if s.private {
+			visibility = 1
+		}
+		s.mu.Lock()
+		name := s.name
+		s.mu.Unlock()
+		if name == "" {
+			name = "gabe"
+		}
+		fmt.Fprintf(w, `{"response": {"players": [{"steamid": %q, "personaname": %q, "communityvisibilitystate": %d}]}}`,
+			r.URL.Query().Get("steamids"), name, visibility)
+	case strings.Contains(r.URL.Path, "GetRecentlyPlayedGames"):
+		if s.recent == "" {
+			w.Write([]byte(`{"response": {"total_count": 0}}`))
+			return
+		}
+		w.Write([]byte(s.recent))
+	case strings.Contains(r.URL.Path, "GetOwnedGames"):
+		w.Write([]byte(`{"response": {"games": [{"appid": 440, "name": "Team Fortress 2", "playtime_forever": 90}]}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *steamStub) count(substr string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for path, c := range s.calls {
+		if strings.Contains(path, substr) {
+			n += c
+		}
+	}
+	return n
+}
+
+func newTestScheduler(t *testing.T, stub *steamStub, opts ...ClientOption) (*Scheduler, *store.SQLiteStore) {
+	t.Helper()
+	stub.calls = make(map[string]int)
+	steam := httptest.NewServer(stub)
+	t.Cleanup(steam.Close)
+
+	db, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	client := NewSteamClient("key", append([]ClientOption{WithBaseURL(steam.URL)}, opts...)...)
+	jobs := NewJobQueue(db)
+	jobs.Register(AchievementSyncJob, func(ctx context.Context, job *models.Job, report func(interface{})) (interface{}, error) {
+		return nil, nil
+	})
+	s := NewScheduler(db, client, NewLibraryService(db), NewPlaytimeService(db), jobs)
+	return s, db
+}
+
+func TestSchedulerFetchesOwnedGamesOncePerUser(t *testing.T) {
+	ctx := context.Background()
+	stub := &steamStub{}
+	s, db := newTestScheduler(t, stub)
+	if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	s.runPass(ctx)
+
+	if n := stub.count("GetOwnedGames"); n != 1 {
+		t.Errorf("pass fetched owned games %d times, want 1", n)
+	}
+	library, err := db.GetLibraryEntries(ctx, schedulerSteamID)
+	if err != nil || library[440] == nil {
+		t.Errorf("library after pass = %v, %v; want app 440", library, err)
+	}
+	totals, err := db.GetPlaytimeTotals(ctx, schedulerSteamID)
+	if err != nil || totals[440] != 90 {
+		t.Errorf("playtime totals after pass = %v, %v; want 440: 90", totals, err)
+	}
+}
+
+func TestSchedulerFetchesProfilesPastTheCache(t *testing.T) {
+	ctx := context.Background()
+	stub := &steamStub{}
+	cache := NewResponseCache(10, nil)
+	s, db := newTestScheduler(t, stub, WithCache(cache))
+	if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	// A request served the profile under its old name, and it is still cached.
+	cached := NewSteamClient("key", WithBaseURL(s.steamClient.baseURL), WithCache(cache))
+	if _, err := cached.GetUserSummary(ctx, schedulerSteamID); err != nil {
+		t.Fatalf("GetUserSummary: %v", err)
+	}
+	stub.mu.Lock()
+	stub.name = "gaben"
+	stub.mu.Unlock()
+
+	s.runPass(ctx)
+
+	if n := stub.count("GetPlayerSummaries"); n != 2 {
+		t.Errorf("GetPlayerSummaries called %d times, want 2", n)
+	}
+	if user, err := db.GetUser(ctx, schedulerSteamID); err != nil || user == nil || user.PersonName != "gaben" {
+		t.Errorf("GetUser after pass = %+v, %v; want the fresh name", user, err)
+	}
+}
+
+func TestSchedulerDoesNotRecreateUserErasedMidPass(t *testing.T) {
+	// Each case erases the user while the pass is waiting on a Steam call.
+	for _, during := range []string{"GetPlayerSummaries", "GetOwnedGames"} {
+		t.Run(during, func(t *testing.T) {
+			ctx := context.Background()
+			stub := &steamStub{}
+			s, db := newTestScheduler(t, stub)
+			if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+				t.Fatalf("SaveUser: %v", err)
+			}
+			stub.before = func(path string) {
+				if strings.Contains(path, during) {
+					if _, err := db.EraseUser(ctx, schedulerSteamID, store.CacheFilter{}); err != nil {
+						t.Errorf("EraseUser: %v", err)
+					}
+				}
+			}
+
+			s.runPass(ctx)
+
+			if user, err := db.GetUser(ctx, schedulerSteamID); err != nil || user != nil {
+				t.Errorf("GetUser after pass = %+v, %v; want the user to stay erased", user, err)
+			}
+			if ids, _ := db.ListUserIDs(ctx); len(ids) != 0 {
+				t.Errorf("ListUserIDs after pass = %v, want none", ids)
+			}
+			if library, _ := db.GetLibraryEntries(ctx, schedulerSteamID); len(library) != 0 {
+				t.Errorf("pass saved library %v for an erased user", library)
+			}
+			if totals, _ := db.GetPlaytimeTotals(ctx, schedulerSteamID); len(totals) != 0 {
+				t.Errorf("pass saved playtime %v for an erased user", totals)
+			}
+			if job, _ := db.FindActiveJob(ctx, AchievementSyncJob, schedulerSteamID); job != nil {
+				t.Errorf("pass queued job %s for an erased user", job.ID)
+			}
+			if n := stub.count("GetOwnedGames"); during == "GetPlayerSummaries" && n != 0 {
+				t.Errorf("pass fetched owned games %d times after the user was erased", n)
+			}
+		})
+	}
+}
+
+func TestSchedulerMergesRecentlyPlayedIntoSnapshot(t *testing.T) {
+	ctx := context.Background()
+	// 440 is owned but the owned list lags; 570 is family-shared and not owned.
+	stub := &steamStub{recent: `{"response": {"total_count": 2, "games": [
+		{"appid": 440, "name": "Team Fortress 2", "playtime_forever": 120},
+		{"appid": 570, "name": "Dota 2", "playtime_forever": 30}]}}`}
+	s, db := newTestScheduler(t, stub)
+	if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	s.runPass(ctx)
+
+	if n := stub.count("GetRecentlyPlayedGames"); n != 1 {
+		t.Errorf("pass fetched recently played games %d times, want 1", n)
+	}
+	totals, err := db.GetPlaytimeTotals(ctx, schedulerSteamID)
+	if err != nil || totals[440] != 120 || totals[570] != 30 {
+		t.Errorf("playtime totals after pass = %v, %v; want 440: 120, 570: 30", totals, err)
+	}
+	library, err := db.GetLibraryEntries(ctx, schedulerSteamID)
+	if err != nil || library[570] != nil {
+		t.Errorf("library after pass = %v, %v; want only owned games", library, err)
+	}
+}
+
+func TestSchedulerPauseAndResume(t *testing.T) {
+	ctx := context.Background()
+	stub := &steamStub{}
+	s, db := newTestScheduler(t, stub)
+	if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	s.Pause()
+	if !s.Status().Paused {
+		t.Fatal("Status().Paused = false after Pause")
+	}
+	s.runPass(ctx)
+	if n := stub.count("GetPlayerSummaries"); n != 0 {
+		t.Errorf("paused pass refreshed %d profiles, want 0", n)
+	}
+
+	s.Resume()
+	if s.Status().Paused {
+		t.Fatal("Status().Paused = true after Resume")
+	}
+	s.runPass(ctx)
+	if n := stub.count("GetPlayerSummaries"); n != 1 {
+		t.Errorf("resumed pass refreshed %d profiles, want 1", n)
+	}
+}
+
+func TestSchedulerBacksOffPrivateProfiles(t *testing.T) {
+	ctx := context.Background()
+	stub := &steamStub{private: true}
+	s, db := newTestScheduler(t, stub)
+	if err := db.SaveUser(ctx, &models.SteamUser{SteamID: schedulerSteamID}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.PrivateBackoff = time.Hour
+	s.MaxBackoff = 3 * time.Hour
+
+	s.runPass(ctx)
+	if n := stub.count("GetOwnedGames"); n != 0 {
+		t.Errorf("pass fetched owned games %d times for a private profile", n)
+	}
+	if got := s.Status().BackedOff; got != 1 {
+		t.Fatalf("BackedOff = %d, want 1", got)
+	}
+
+	// Within the backoff the user is skipped entirely.
+	s.runPass(ctx)
+	if n := stub.count("GetPlayerSummaries"); n != 1 {
+		t.Errorf("profile fetched %d times during backoff, want 1", n)
+	}
+
+	// Each further private result doubles the backoff, up to MaxBackoff.
+	for i, want := range []time.Duration{2 * time.Hour, 3 * time.Hour, 3 * time.Hour} {
+		now = s.backoffs[schedulerSteamID].until
+		s.runPass(ctx)
+		if got := s.backoffs[schedulerSteamID].until.Sub(now); got != want {
+			t.Errorf("backoff %d = %s, want %s", i+2, got, want)
+		}
+	}
+
+	// A public profile clears the backoff.
+	stub.private = false
+	now = s.backoffs[schedulerSteamID].until
+	s.runPass(ctx)
+	if got := s.Status().BackedOff; got != 0 {
+		t.Errorf("BackedOff after a public refresh = %d, want 0", got)
+	}
+	if n := stub.count("GetOwnedGames"); n != 1 {
+		t.Errorf("public refresh fetched owned games %d times, want 1", n)
+	}
+}
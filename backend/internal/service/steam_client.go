@@ -2,10 +2,17 @@ package service
 
 import (
 	"backend/internal/models"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,43 +20,256 @@ type SteamClient struct {
 	apiKey     string
 	httpClient *http.Client
 	baseURL    string
+	// storeBaseURL serves the storefront API (appdetails), which is keyless
+	// and has its own, much lower, rate limit.
+	storeBaseURL string
+	cache        *ResponseCache
+	limiter      *RateLimiter
+	storeLimiter *RateLimiter
+	retry        RetryPolicy
+	stats        *clientStats
+	// bypassCache skips cache reads but still refreshes the cache with the fresh response.
+	bypassCache bool
 }
 
-func NewSteamClient(apiKey string) *SteamClient {
-	return &SteamClient{
-		apiKey:  apiKey,
-		baseURL: "https://api.steampowered.com",
+// ClientOption configures optional SteamClient behaviour.
+type ClientOption func(*SteamClient)
+
+// WithCache enables response caching for endpoints that have a TTL configured.
+func WithCache(cache *ResponseCache) ClientOption {
+	return func(s *SteamClient) {
+		s.cache = cache
+	}
+}
+
+// WithRateLimiter throttles outbound requests through a shared token bucket.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(s *SteamClient) {
+		s.limiter = limiter
+	}
+}
+
+// WithStoreRateLimiter throttles storefront requests separately from Web API calls.
+func WithStoreRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(s *SteamClient) {
+		s.storeLimiter = limiter
+	}
+}
+
+// WithBaseURL points the client at a different Web API host, e.g. a local stub.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(s *SteamClient) {
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithStoreBaseURL points storefront requests at a different host, e.g. a local stub.
+func WithStoreBaseURL(baseURL string) ClientOption {
+	return func(s *SteamClient) {
+		s.storeBaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(s *SteamClient) {
+		s.retry = policy
+	}
+}
+
+func NewSteamClient(apiKey string, opts ...ClientOption) *SteamClient {
+	s := &SteamClient{
+		apiKey:       apiKey,
+		baseURL:      "https://api.steampowered.com",
+		storeBaseURL: "https://store.steampowered.com",
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		retry: DefaultRetryPolicy,
+		stats: &clientStats{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *SteamClient) get(path string, query url.Values, target interface{}) error {
+// WithoutCache returns a copy of the client that always goes to Steam,
+// used when a caller sends Cache-Control: no-cache.
+func (s *SteamClient) WithoutCache() *SteamClient {
+	c := *s
+	c.bypassCache = true
+	return &c
+}
+
+// ClientStats is a snapshot of outbound Steam traffic counters.
+type ClientStats struct {
+	Requests    int64 `json:"requests"`
+	CacheHits   int64 `json:"cacheHits"`
+	Retries     int64 `json:"retries"`
+	RateLimited int64 `json:"rateLimited"`
+	Failures    int64 `json:"failures"`
+	// ThrottleWaitMs is the total time spent waiting on the local rate limiter.
+	ThrottleWaitMs int64 `json:"throttleWaitMs"`
+}
+
+type clientStats struct {
+	requests     atomic.Int64
+	cacheHits    atomic.Int64
+	retries      atomic.Int64
+	rateLimited  atomic.Int64
+	failures     atomic.Int64
+	throttleWait atomic.Int64
+}
+
+// Stats returns the client's traffic counters since startup.
+func (s *SteamClient) Stats() ClientStats {
+	return ClientStats{
+		Requests:       s.stats.requests.Load(),
+		CacheHits:      s.stats.cacheHits.Load(),
+		Retries:        s.stats.retries.Load(),
+		RateLimited:    s.stats.rateLimited.Load(),
+		Failures:       s.stats.failures.Load(),
+		ThrottleWaitMs: time.Duration(s.stats.throttleWait.Load()).Milliseconds(),
+	}
+}
+
+func (s *SteamClient) get(ctx context.Context, path string, query url.Values, target interface{}) error {
+	// The cache key is built before the API key is added so it never ends up in storage.
+	cacheKey := path + "?" + query.Encode()
+	var ttl time.Duration
+	if s.cache != nil {
+		ttl = s.cache.TTL(path)
+	}
+	if ttl > 0 && !s.bypassCache {
+		if body, ok := s.cache.Get(ctx, cacheKey); ok {
+			s.stats.cacheHits.Add(1)
+			return json.Unmarshal(body, target)
+		}
+	}
+
 	query.Set("key", s.apiKey)
 	u := fmt.Sprintf("%s%s?%s", s.baseURL, path, query.Encode())
 
-	resp, err := s.httpClient.Get(u)
+	body, err := s.fetch(ctx, s.limiter, path, u)
 	if err != nil {
-		fmt.Printf("HTTP Request failed: %v\n", err)
+		s.stats.failures.Add(1)
+		return err
+	}
+	if err := json.Unmarshal(body, target); err != nil {
 		return err
 	}
+	if ttl > 0 {
+		s.cache.Set(ctx, cacheKey, body, ttl)
+	}
+	return nil
+}
+
+// getStore calls the storefront API. Responses are not cached here; callers
+// persist what they need.
+func (s *SteamClient) getStore(ctx context.Context, path string, query url.Values, target interface{}) error {
+	u := fmt.Sprintf("%s%s?%s", s.storeBaseURL, path, query.Encode())
+
+	body, err := s.fetch(ctx, s.storeLimiter, path, u)
+	if err != nil {
+		s.stats.failures.Add(1)
+		return err
+	}
+	return json.Unmarshal(body, target)
+}
+
+// fetch performs the request, retrying transient failures within the retry policy.
+func (s *SteamClient) fetch(ctx context.Context, limiter *RateLimiter, path, u string) ([]byte, error) {
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if d := limiter.Reserve(); d > 0 {
+				s.stats.throttleWait.Add(int64(d))
+				if err := sleep(ctx, d); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		s.stats.requests.Add(1)
+		body, status, retryAfter, err := s.doOnce(ctx, u)
+		if err == nil && status == http.StatusOK {
+			return body, nil
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if status == http.StatusTooManyRequests {
+			s.stats.rateLimited.Add(1)
+		}
+		apiErr := &SteamAPIError{Path: path, StatusCode: status, RetryAfter: retryAfter}
+		if err != nil {
+			// url.Error carries the full URL, API key included; keep only the cause.
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			apiErr.Kind = ErrUpstreamUnavailable
+			apiErr.cause = err
+		} else {
+			apiErr.Kind = classifyStatus(path, status, body)
+		}
+		err = apiErr
+
+		retryable := status == 0 || retryableStatus(status)
+		if !retryable || attempt >= s.retry.MaxRetries {
+			return nil, err
+		}
+		if retryAfter > s.retry.MaxDelay {
+			return nil, err
+		}
+		delay := s.retry.backoff(attempt, retryAfter)
+		if waited+delay > s.retry.Budget {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+		waited += delay
+		s.stats.retries.Add(1)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doOnce makes a single HTTP call. A zero status means the request never got a response.
+func (s *SteamClient) doOnce(ctx context.Context, u string) ([]byte, int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Steam API returned %d for %s\n", resp.StatusCode, u)
-		return fmt.Errorf("steam api error: status %d", resp.StatusCode)
+		// Error bodies are only inspected for classification, so a prefix is enough.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return body, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), nil
 	}
 
-	return json.NewDecoder(resp.Body).Decode(target)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return body, resp.StatusCode, 0, nil
 }
 
-func (s *SteamClient) GetUserSummary(steamID string) (*models.SteamUser, error) {
+func (s *SteamClient) GetUserSummary(ctx context.Context, steamID string) (*models.SteamUser, error) {
 	q := url.Values{}
 	q.Set("steamids", steamID)
 
 	var resp models.PlayerSummariesResponse
-	err := s.get("/ISteamUser/GetPlayerSummaries/v0002/", q, &resp)
+	err := s.get(ctx, "/ISteamUser/GetPlayerSummaries/v0002/", q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +280,7 @@ func (s *SteamClient) GetUserSummary(steamID string) (*models.SteamUser, error)
 	return nil, nil // Not found
 }
 
-func (s *SteamClient) GetOwnedGames(steamID string) ([]models.SteamGame, error) {
+func (s *SteamClient) GetOwnedGames(ctx context.Context, steamID string) ([]models.SteamGame, error) {
 	q := url.Values{}
 	q.Set("steamid", steamID)
 	q.Set("format", "json")
@@ -68,7 +288,7 @@ func (s *SteamClient) GetOwnedGames(steamID string) ([]models.SteamGame, error)
 	q.Set("include_played_free_games", "true")
 
 	var resp models.OwnedGamesResponse
-	err := s.get("/IPlayerService/GetOwnedGames/v0001/", q, &resp)
+	err := s.get(ctx, "/IPlayerService/GetOwnedGames/v0001/", q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -76,39 +296,49 @@ func (s *SteamClient) GetOwnedGames(steamID string) ([]models.SteamGame, error)
 	return resp.Response.Games, nil
 }
 
-func (s *SteamClient) GetPlayerAchievements(steamID string, appID int) ([]models.SteamAchievement, error) {
-	// 1. Get Player Status
-	qStat := url.Values{}
-	qStat.Set("steamid", steamID)
-	qStat.Set("appid", fmt.Sprintf("%d", appID))
+// AchievementOptions controls how GetPlayerAchievements presents achievements.
+type AchievementOptions struct {
+	// Language is a Steam language code (e.g. "german", "french") for names and
+	// descriptions. Empty means Steam's default, English.
+	Language string
+	// RevealHidden includes names and descriptions of hidden achievements that are still locked.
+	RevealHidden bool
+}
 
-	var statResp models.PlayerAchievementsResponse
-	err := s.get("/ISteamUserStats/GetPlayerAchievements/v0001/", qStat, &statResp)
+func (s *SteamClient) GetPlayerAchievements(ctx context.Context, steamID string, appID int, opts AchievementOptions) ([]models.SteamAchievement, error) {
+	// 1. Get Player Status
+	statResp, err := s.getAchievementStates(ctx, steamID, appID, opts.Language)
 	if err != nil {
-		fmt.Printf("Error fetching player achievements structure: %v\n", err)
-		return nil, err // Often fails if profile is private or game has no stats
+		return nil, err
 	}
 
 	// 2. Get Schema (Optional, simplified for now)
-	qSchema := url.Values{}
-	qSchema.Set("appid", fmt.Sprintf("%d", appID))
-
-	var schemaResp models.GameSchemaResponse
-	schemaMap := make(map[string]struct {
+	type schemaEntry struct {
 		Name        string
 		Description string
-	})
-	
+		Icon        string
+		IconGray    string
+		Hidden      bool
+	}
+	schemaMap := make(map[string]schemaEntry)
+
 	// We consume schema error softly
-	if err := s.get("/ISteamUserStats/GetSchemaForGame/v2/", qSchema, &schemaResp); err == nil {
+	if schemaResp, err := s.getSchema(ctx, appID, opts.Language); err == nil {
 		for _, a := range schemaResp.Game.AvailableGameStats.Achievements {
-			schemaMap[a.Name] = struct {
-				Name        string
-				Description string
-			}{Name: a.DisplayName, Description: a.Description}
+			schemaMap[a.Name] = schemaEntry{
+				Name:        a.DisplayName,
+				Description: a.Description,
+				Icon:        a.Icon,
+				IconGray:    a.IconGray,
+				Hidden:      a.Hidden == 1,
+			}
 		}
 	}
 
+	// 3. Get global unlock rates, also consumed softly; without them
+	// achievements simply carry no rarity.
+	percentages, _ := s.GetGlobalAchievementPercentages(ctx, appID)
+
 	var result []models.SteamAchievement
 	for _, a := range statResp.PlayerStats.Achievements {
 		schema := schemaMap[a.APIName]
@@ -116,74 +346,277 @@ func (s *SteamClient) GetPlayerAchievements(steamID string, appID int) ([]models
 		if name == "" {
 			name = a.APIName
 		}
-		
-		result = append(result, models.SteamAchievement{
+
+		description := schema.Description
+		if schema.Hidden && a.Achieved != 1 && !opts.RevealHidden {
+			// The name gives a hidden achievement away as much as its description.
+			name, description = "", ""
+		}
+
+		achievement := models.SteamAchievement{
 			APIName:     a.APIName,
 			Achieved:    a.Achieved == 1,
 			UnlockTime:  a.UnlockTime,
 			Name:        name,
-			Description: schema.Description,
-		})
+			Description: description,
+			Icon:        schema.Icon,
+			IconGray:    schema.IconGray,
+			Hidden:      schema.Hidden,
+		}
+		if percent, ok := percentages[a.APIName]; ok {
+			achievement.GlobalPercent = &percent
+		}
+		result = append(result, achievement)
+	}
+
+	return result, nil
+}
+
+// getAchievementStates fetches which achievements the player has unlocked, without schema details.
+func (s *SteamClient) getAchievementStates(ctx context.Context, steamID string, appID int, language string) (*models.PlayerAchievementsResponse, error) {
+	qStat := url.Values{}
+	qStat.Set("steamid", steamID)
+	qStat.Set("appid", fmt.Sprintf("%d", appID))
+	if language != "" {
+		qStat.Set("l", language)
+	}
+
+	var statResp models.PlayerAchievementsResponse
+	err := s.get(ctx, "/ISteamUserStats/GetPlayerAchievements/v0001/", qStat, &statResp)
+	if err != nil {
+		// Often fails if the profile is private or the game has no stats.
+		return nil, fmt.Errorf("achievements for app %d: %w", appID, err)
+	}
+	if !statResp.PlayerStats.Success {
+		if kind := classifyStatsError(statResp.PlayerStats.Error); kind != nil {
+			return nil, fmt.Errorf("achievements for app %d: %w", appID, kind)
+		}
+		if statResp.PlayerStats.SteamID == "" {
+			// An empty playerstats object is what Steam sends for private profiles.
+			return nil, fmt.Errorf("achievements for app %d: %w", appID, ErrPrivateProfile)
+		}
+	}
+	return &statResp, nil
+}
+
+// GetPlayerStats returns the player's numeric stats for appID joined with their
+// display names from the game schema. Stats the player has never changed are
+// omitted by Steam and reported here with the schema's default value.
+func (s *SteamClient) GetPlayerStats(ctx context.Context, steamID string, appID int, language string) ([]models.SteamStat, error) {
+	q := url.Values{}
+	q.Set("steamid", steamID)
+	q.Set("appid", fmt.Sprintf("%d", appID))
+
+	var statsResp models.UserStatsForGameResponse
+	if err := s.get(ctx, "/ISteamUserStats/GetUserStatsForGame/v0002/", q, &statsResp); err != nil {
+		// Steam answers 400 "Requested app has no stats" for games without stats.
+		return nil, fmt.Errorf("stats for app %d: %w", appID, err)
+	}
+	if statsResp.PlayerStats.SteamID == "" {
+		// Same as for achievements: an empty playerstats object means a private profile.
+		return nil, fmt.Errorf("stats for app %d: %w", appID, ErrPrivateProfile)
 	}
 
+	values := make(map[string]float64, len(statsResp.PlayerStats.Stats))
+	for _, st := range statsResp.PlayerStats.Stats {
+		values[st.Name] = st.Value
+	}
+
+	// The schema is optional here too; without it stats keep their API names.
+	result := []models.SteamStat{}
+	seen := make(map[string]bool)
+	if schemaResp, err := s.getSchema(ctx, appID, language); err == nil {
+		if len(schemaResp.Game.AvailableGameStats.Stats) == 0 && len(values) == 0 {
+			// Games with achievements but no stats have an empty stats schema.
+			return nil, fmt.Errorf("stats for app %d: %w", appID, ErrNoStats)
+		}
+		for _, st := range schemaResp.Game.AvailableGameStats.Stats {
+			value, ok := values[st.Name]
+			if !ok {
+				value = st.DefaultValue
+			}
+			displayName := st.DisplayName
+			if displayName == "" {
+				displayName = st.Name
+			}
+			result = append(result, models.SteamStat{APIName: st.Name, DisplayName: displayName, Value: value})
+			seen[st.Name] = true
+		}
+	}
+	for _, st := range statsResp.PlayerStats.Stats {
+		if !seen[st.Name] {
+			result = append(result, models.SteamStat{APIName: st.Name, DisplayName: st.Name, Value: st.Value})
+		}
+	}
 	return result, nil
 }
 
-func (s *SteamClient) GetFriendList(steamID string) ([]models.SteamUser, error) {
+// getSchema fetches the achievement and stat definitions for appID.
+func (s *SteamClient) getSchema(ctx context.Context, appID int, language string) (*models.GameSchemaResponse, error) {
+	q := url.Values{}
+	q.Set("appid", fmt.Sprintf("%d", appID))
+	if language != "" {
+		q.Set("l", language)
+	}
+
+	var resp models.GameSchemaResponse
+	if err := s.get(ctx, "/ISteamUserStats/GetSchemaForGame/v2/", q, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetGlobalAchievementPercentages returns, per achievement API name, the
+// percentage of all players of appID that have unlocked it.
+func (s *SteamClient) GetGlobalAchievementPercentages(ctx context.Context, appID int) (map[string]float64, error) {
+	q := url.Values{}
+	q.Set("gameid", fmt.Sprintf("%d", appID))
+
+	var resp models.GlobalAchievementPercentagesResponse
+	if err := s.get(ctx, "/ISteamUserStats/GetGlobalAchievementPercentagesForApp/v0002/", q, &resp); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64, len(resp.AchievementPercentages.Achievements))
+	for _, a := range resp.AchievementPercentages.Achievements {
+		percent, err := a.Percent.Float64()
+		if err != nil {
+			continue
+		}
+		result[a.Name] = percent
+	}
+	return result, nil
+}
+
+func (s *SteamClient) GetFriendList(ctx context.Context, steamID string) ([]models.SteamFriend, error) {
 	// 1. Get Friend IDs
 	q := url.Values{}
 	q.Set("steamid", steamID)
 	q.Set("relationship", "friend")
 
 	var friendsResp models.FriendListResponse
-	if err := s.get("/ISteamUser/GetFriendList/v0001/", q, &friendsResp); err != nil {
+	if err := s.get(ctx, "/ISteamUser/GetFriendList/v0001/", q, &friendsResp); err != nil {
 		return nil, err
 	}
 
-	if len(friendsResp.FriendsList.Friends) == 0 {
-		return []models.SteamUser{}, nil
+	friends := friendsResp.FriendsList.Friends
+	if len(friends) == 0 {
+		return []models.SteamFriend{}, nil
 	}
 
 	// 2. Get Summaries for Friend IDs
-	// Limit to first 100 for now to match Dart logic and API limits
-	var friendIDs []string
-	count := 0
-	for _, f := range friendsResp.FriendsList.Friends {
-		friendIDs = append(friendIDs, f.SteamID)
-		count++
-		if count >= 100 {
-			break
+	friendIDs := make([]string, len(friends))
+	for i, f := range friends {
+		friendIDs[i] = f.SteamID
+	}
+	players, err := s.GetPlayerSummaries(ctx, friendIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.SteamUser, len(players))
+	for _, p := range players {
+		byID[p.SteamID] = p
+	}
+
+	// Keep the friend list order; friends without a summary (deleted accounts) are dropped.
+	result := make([]models.SteamFriend, 0, len(friends))
+	for _, f := range friends {
+		user, ok := byID[f.SteamID]
+		if !ok {
+			continue
 		}
+		result = append(result, models.SteamFriend{
+			SteamUser:    user,
+			Relationship: f.Relationship,
+			FriendSince:  f.FriendSince,
+		})
 	}
+	return result, nil
+}
 
-	// Reuse GetUserSummary logic but for bulk?
-	// The current GetUserSummary takes one ID. We need a bulk fetcher or reuse the underlying call.
-	// Let's call the API directly here for bulk.
-	qSum := url.Values{}
-	qSum.Set("steamids", JoinSteamIDs(friendIDs)) // Helper function or strings.Join
+// maxSummaryIDs is the most steamids GetPlayerSummaries accepts per call.
+const maxSummaryIDs = 100
 
-	var playersResp models.PlayerSummariesResponse
-	if err := s.get("/ISteamUser/GetPlayerSummaries/v0002/", qSum, &playersResp); err != nil {
-		return nil, err
+// summaryConcurrency bounds how many GetPlayerSummaries chunks are in flight at once.
+const summaryConcurrency = 4
+
+// GetPlayerSummaries fetches profiles for any number of IDs, splitting them into
+// chunks of 100. Players are returned in chunk order; Steam does not guarantee
+// order within a chunk, so callers that care should re-order by SteamID.
+func (s *SteamClient) GetPlayerSummaries(ctx context.Context, steamIDs []string) ([]models.SteamUser, error) {
+	var chunks [][]string
+	for start := 0; start < len(steamIDs); start += maxSummaryIDs {
+		chunks = append(chunks, steamIDs[start:min(start+maxSummaryIDs, len(steamIDs))])
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]models.SteamUser, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, summaryConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			q := url.Values{}
+			q.Set("steamids", JoinSteamIDs(chunk))
+			var resp models.PlayerSummariesResponse
+			if err := s.get(ctx, "/ISteamUser/GetPlayerSummaries/v0002/", q, &resp); err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			results[i] = resp.Response.Players
+		}()
+	}
+	wg.Wait()
+
+	// Report the root cause rather than the cancellations it triggered in other chunks.
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
 	}
 
-	return playersResp.Response.Players, nil
+	var players []models.SteamUser
+	for _, r := range results {
+		players = append(players, r...)
+	}
+	return players, nil
 }
 
-func (s *SteamClient) GetRecentlyPlayedGames(steamID string) ([]models.SteamGame, error) {
+func (s *SteamClient) GetRecentlyPlayedGames(ctx context.Context, steamID string) ([]models.SteamGame, error) {
 	q := url.Values{}
 	q.Set("steamid", steamID)
 	q.Set("count", "10")
 
 	var resp models.RecentlyPlayedGamesResponse
-	if err := s.get("/IPlayerService/GetRecentlyPlayedGames/v0001/", q, &resp); err != nil {
+	if err := s.get(ctx, "/IPlayerService/GetRecentlyPlayedGames/v0001/", q, &resp); err != nil {
 		return nil, err
 	}
 
 	return resp.Response.Games, nil
 }
 
-func (s *SteamClient) GetSteamLevel(steamID string) (int, error) {
+func (s *SteamClient) GetSteamLevel(ctx context.Context, steamID string) (int, error) {
 	q := url.Values{}
 	q.Set("steamid", steamID)
 
@@ -192,20 +625,20 @@ func (s *SteamClient) GetSteamLevel(steamID string) (int, error) {
 			PlayerLevel int `json:"player_level"`
 		} `json:"response"`
 	}
-	if err := s.get("/IPlayerService/GetSteamLevel/v1/", q, &resp); err != nil {
+	if err := s.get(ctx, "/IPlayerService/GetSteamLevel/v1/", q, &resp); err != nil {
 		return 0, err
 	}
 	return resp.Response.PlayerLevel, nil
 }
 
-func (s *SteamClient) GetPlayerBans(steamID string) (map[string]interface{}, error) {
+func (s *SteamClient) GetPlayerBans(ctx context.Context, steamID string) (map[string]interface{}, error) {
 	q := url.Values{}
 	q.Set("steamids", steamID)
 
 	var resp struct {
 		Players []map[string]interface{} `json:"players"`
 	}
-	if err := s.get("/ISteamUser/GetPlayerBans/v1/", q, &resp); err != nil {
+	if err := s.get(ctx, "/ISteamUser/GetPlayerBans/v1/", q, &resp); err != nil {
 		return nil, err
 	}
 
@@ -215,7 +648,7 @@ func (s *SteamClient) GetPlayerBans(steamID string) (map[string]interface{}, err
 	return map[string]interface{}{}, nil
 }
 
-func (s *SteamClient) GetBadges(steamID string) (map[string]interface{}, error) {
+func (s *SteamClient) GetBadges(ctx context.Context, steamID string) (map[string]interface{}, error) {
 	q := url.Values{}
 	q.Set("steamid", steamID)
 
@@ -223,7 +656,7 @@ func (s *SteamClient) GetBadges(steamID string) (map[string]interface{}, error)
 		Response map[string]interface{} `json:"response"`
 	}
 	// Note: GetBadges/v1 might return 400/403 if profile private, Handle gracefully?
-	if err := s.get("/IPlayerService/GetBadges/v1/", q, &resp); err != nil {
+	if err := s.get(ctx, "/IPlayerService/GetBadges/v1/", q, &resp); err != nil {
 		return nil, err
 	}
 	return resp.Response, nil
@@ -240,3 +673,22 @@ func JoinSteamIDs(ids []string) string {
 	}
 	return res
 }
+
+// GetAppDetails fetches storefront metadata for a single app. It returns nil
+// when the store has no page for the app (delisted, tools, soundtracks, ...).
+func (s *SteamClient) GetAppDetails(ctx context.Context, appID int) (*models.AppDetails, error) {
+	q := url.Values{}
+	q.Set("appids", strconv.Itoa(appID))
+	q.Set("l", "english")
+
+	var resp models.AppDetailsResponse
+	if err := s.getStore(ctx, "/api/appdetails", q, &resp); err != nil {
+		return nil, err
+	}
+
+	entry, ok := resp[strconv.Itoa(appID)]
+	if !ok || !entry.Success {
+		return nil, nil
+	}
+	return &entry.Data, nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetFriendListChunksSummaries(t *testing.T) {
+	const (
+		friends = 650
+		// A friend whose account was deleted has no summary.
+		deleted = 321
+	)
+	ids := make([]string, friends)
+	for i := range ids {
+		ids[i] = strconv.FormatUint(76561197960265728+uint64(i), 10)
+	}
+
+	var (
+		mu       sync.Mutex
+		chunks   []int
+		inFlight int
+		peak     int
+	)
+	// The first chunks wait until summaryConcurrency are in flight together.
+	full := make(chan struct{})
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "GetFriendList"):
+			var resp struct {
+				FriendsList struct {
+					Friends []map[string]interface{} `json:"friends"`
+				} `json:"friendslist"`
+			}
+			for i, id := range ids {
+				resp.FriendsList.Friends = append(resp.FriendsList.Friends,
+					map[string]interface{}{"steamid": id, "relationship": "friend", "friend_since": i})
+			}
+			json.NewEncoder(w).Encode(resp)
+
+		case strings.Contains(r.URL.Path, "GetPlayerSummaries"):
+			requested := strings.Split(r.URL.Query().Get("steamids"), ",")
+			mu.Lock()
+			chunks = append(chunks, len(requested))
+			inFlight++
+			peak = max(peak, inFlight)
+			if inFlight == summaryConcurrency {
+				close(full)
+			}
+			first := len(chunks) <= summaryConcurrency
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+			if first {
+				select {
+				case <-full:
+				case <-time.After(5 * time.Second):
+					t.Error("summary chunks were never fetched concurrently")
+				}
+			}
+			// The chunk holding the first friends finishes last.
+			if requested[0] == ids[0] {
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			// Steam does not keep the requested order within a chunk.
+			var resp struct {
+				Response struct {
+					Players []map[string]string `json:"players"`
+				} `json:"response"`
+			}
+			for i := len(requested) - 1; i >= 0; i-- {
+				if requested[i] != ids[deleted] {
+					resp.Response.Players = append(resp.Response.Players,
+						map[string]string{"steamid": requested[i], "personaname": "friend " + requested[i]})
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer steam.Close()
+
+	client := NewSteamClient("key", WithBaseURL(steam.URL))
+	got, err := client.GetFriendList(context.Background(), ids[0])
+	if err != nil {
+		t.Fatalf("GetFriendList: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	total := 0
+	for _, n := range chunks {
+		if n > maxSummaryIDs {
+			t.Errorf("a summaries request asked for %d IDs, more than %d", n, maxSummaryIDs)
+		}
+		total += n
+	}
+	if len(chunks) != 7 || total != friends {
+		t.Errorf("summaries requested in %d chunks of %v, want 7 covering all %d friends", len(chunks), chunks, friends)
+	}
+	if peak != summaryConcurrency {
+		t.Errorf("at most %d summary requests in flight, want %d", peak, summaryConcurrency)
+	}
+
+	if len(got) != friends-1 {
+		t.Fatalf("GetFriendList returned %d friends, want %d", len(got), friends-1)
+	}
+	want := append(append([]string{}, ids[:deleted]...), ids[deleted+1:]...)
+	for i, f := range got {
+		if f.SteamID != want[i] || f.PersonName != "friend "+want[i] {
+			t.Fatalf("friend %d = %s (%q), want %s in friend-list order", i, f.SteamID, f.PersonName, want[i])
+		}
+		if i < deleted && f.FriendSince != i {
+			t.Fatalf("friend %d has friend_since %d, want %d", i, f.FriendSince, i)
+		}
+	}
+}
+
+func TestGetPlayerAchievementsMasksHiddenAchievements(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		languages = make(map[string]string)
+	)
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		languages[r.URL.Path] = r.URL.Query().Get("l")
+		mu.Unlock()
+		switch {
+		case strings.Contains(r.URL.Path, "GetPlayerAchievements"):
+			w.Write([]byte(`{"playerstats": {"steamID": "76561197960287930", "success": true, "achievements": [
+				{"apiname": "SECRET_LOCKED", "achieved": 0, "unlocktime": 0},
+				{"apiname": "SECRET_DONE", "achieved": 1, "unlocktime": 1700000000},
+				{"apiname": "PLAIN", "achieved": 0, "unlocktime": 0}]}}`))
+		case strings.Contains(r.URL.Path, "GetSchemaForGame"):
+			w.Write([]byte(`{"game": {"availableGameStats": {"achievements": [
+				{"name": "SECRET_LOCKED", "displayName": "Verrat", "description": "Der Boss ist dein Vater", "hidden": 1, "icon": "on.jpg", "icongray": "off.jpg"},
+				{"name": "SECRET_DONE", "displayName": "Ende", "description": "Sieh den Abspann", "hidden": 1},
+				{"name": "PLAIN", "displayName": "Erste Schritte", "description": "Starte das Spiel", "hidden": 0}]}}}`))
+		case strings.Contains(r.URL.Path, "GetGlobalAchievementPercentagesForApp"):
+			w.Write([]byte(`{"achievementpercentages": {"achievements": []}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer steam.Close()
+	client := NewSteamClient("key", WithBaseURL(steam.URL))
+
+	type shown struct{ name, description string }
+	tests := []struct {
+		reveal bool
+		want   map[string]shown
+	}{
+		{false, map[string]shown{
+			"SECRET_LOCKED": {"", ""},
+			"SECRET_DONE":   {"Ende", "Sieh den Abspann"},
+			"PLAIN":         {"Erste Schritte", "Starte das Spiel"},
+		}},
+		{true, map[string]shown{
+			"SECRET_LOCKED": {"Verrat", "Der Boss ist dein Vater"},
+			"SECRET_DONE":   {"Ende", "Sieh den Abspann"},
+			"PLAIN":         {"Erste Schritte", "Starte das Spiel"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := client.GetPlayerAchievements(context.Background(), "76561197960287930", 440,
+			AchievementOptions{Language: "german", RevealHidden: tt.reveal})
+		if err != nil {
+			t.Fatalf("GetPlayerAchievements: %v", err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("got %d achievements, want %d", len(got), len(tt.want))
+		}
+		for _, a := range got {
+			if w := tt.want[a.APIName]; a.Name != w.name || a.Description != w.description {
+				t.Errorf("reveal=%v: %s shows %q / %q, want %q / %q", tt.reveal, a.APIName, a.Name, a.Description, w.name, w.description)
+			}
+			if a.Hidden != (a.APIName != "PLAIN") {
+				t.Errorf("%s hidden = %v", a.APIName, a.Hidden)
+			}
+		}
+		// Icons stay, so the app can show the locked artwork.
+		if got[0].Icon != "on.jpg" || got[0].IconGray != "off.jpg" {
+			t.Errorf("SECRET_LOCKED icons = %q, %q", got[0].Icon, got[0].IconGray)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for path, l := range languages {
+		if !strings.Contains(path, "GetGlobalAchievementPercentagesForApp") && l != "german" {
+			t.Errorf("%s was called with l=%q, want german", path, l)
+		}
+	}
+}
+
+func TestGetPlayerStats(t *testing.T) {
+	const playerStats = `{"playerstats": {"steamID": "76561197960287930", "gameName": "Game", "stats": [
+		{"name": "kills", "value": 42},
+		{"name": "legacy_stat", "value": 7}]}}`
+	const statsSchema = `{"game": {"availableGameStats": {"stats": [
+		{"name": "kills", "defaultvalue": 0, "displayName": "Kills"},
+		{"name": "wins", "defaultvalue": 0, "displayName": "Wins"},
+		{"name": "distance", "defaultvalue": 1.5, "displayName": ""}]}}}`
+
+	tests := []struct {
+		name    string
+		stats   string
+		status  int
+		schema  string
+		want    []models.SteamStat
+		wantErr error
+	}{
+		{
+			name:   "joined with schema",
+			stats:  playerStats,
+			status: http.StatusOK,
+			schema: statsSchema,
+			// Stats the player lacks get the schema default; stats missing from
+			// the schema keep their API name.
+			want: []models.SteamStat{
+				{APIName: "kills", DisplayName: "Kills", Value: 42},
+				{APIName: "wins", DisplayName: "Wins", Value: 0},
+				{APIName: "distance", DisplayName: "distance", Value: 1.5},
+				{APIName: "legacy_stat", DisplayName: "legacy_stat", Value: 7},
+			},
+		},
+		{
+			name:   "schema unavailable",
+			stats:  playerStats,
+			status: http.StatusOK,
+			want: []models.SteamStat{
+				{APIName: "kills", DisplayName: "kills", Value: 42},
+				{APIName: "legacy_stat", DisplayName: "legacy_stat", Value: 7},
+			},
+		},
+		{name: "empty SteamID", stats: `{"playerstats": {}}`, status: http.StatusOK, schema: statsSchema, wantErr: ErrPrivateProfile},
+		{name: "no stats schema", stats: `{"playerstats": {"steamID": "76561197960287930", "gameName": "Game"}}`, status: http.StatusOK,
+			schema: `{"game": {"gameName": "Game", "availableGameStats": {"achievements": []}}}`, wantErr: ErrNoStats},
+		{name: "no stats upstream", stats: `{"playerstats": {"error": "Requested app has no stats"}}`, status: http.StatusBadRequest, schema: statsSchema, wantErr: ErrNoStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "GetUserStatsForGame"):
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.stats))
+				case strings.Contains(r.URL.Path, "GetSchemaForGame") && tt.schema != "":
+					w.Write([]byte(tt.schema))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer steam.Close()
+			client := NewSteamClient("key", WithBaseURL(steam.URL), WithRetryPolicy(RetryPolicy{}))
+
+			got, err := client.GetPlayerStats(context.Background(), "76561197960287930", 440, "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetPlayerStats error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPlayerStats: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPlayerStats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
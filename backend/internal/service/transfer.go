@@ -0,0 +1,391 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransferService exports a user's tracker data and imports it back, on
+// this instance or another.
+type TransferService struct {
+	store       store.Store
+	library     store.LibraryStore
+	steamClient *SteamClient
+	now         func() time.Time
+}
+
+// NewTransferService returns a TransferService. steamClient may be nil, in
+// which case exported names only come from the stored library.
+func NewTransferService(store store.Store, library store.LibraryStore, steamClient *SteamClient) *TransferService {
+	return &TransferService{store: store, library: library, steamClient: steamClient, now: time.Now}
+}
+
+// Export returns the user's tracker data ordered by app ID. Names come from
+// the library as last refreshed by the scheduler, or else from the user's
+// owned games on Steam. If Steam can't be reached those games have no name.
+func (t *TransferService) Export(ctx context.Context, steamID string) (*models.Export, error) {
+	data, err := t.store.QueryGameData(ctx, steamID, store.GameDataQuery{Sort: store.SortByAppID})
+	if err != nil {
+		return nil, err
+	}
+	library, err := t.library.GetLibraryEntries(ctx, steamID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.Export{
+		SteamID:    steamID,
+		ExportedAt: t.now().UTC().Truncate(time.Second),
+		Games:      make([]models.ExportedGame, 0, len(data)),
+	}
+	for _, d := range data {
+		game := models.ExportedGame{
+			AppID:      d.AppID,
+			Status:     d.Status.String(),
+			Rating:     d.Rating,
+			IsFavorite: d.IsFavorite,
+			PlayOrder:  d.PlayOrder,
+			Notes:      d.Notes,
+			CreatedAt:  d.CreatedAt.UTC(),
+			UpdatedAt:  d.UpdatedAt.UTC(),
+		}
+		if entry := library[d.AppID]; entry != nil {
+			game.Name = entry.Name
+		}
+		export.Games = append(export.Games, game)
+	}
+	t.fillNames(ctx, steamID, export.Games)
+	return export, nil
+}
+
+// fillNames names games the library has no name for from the user's owned
+// games. A user the scheduler hasn't refreshed yet has no library at all.
+func (t *TransferService) fillNames(ctx context.Context, steamID string, games []models.ExportedGame) {
+	missing := false
+	for _, g := range games {
+		missing = missing || g.Name == ""
+	}
+	if !missing || t.steamClient == nil {
+		return
+	}
+
+	owned, err := t.steamClient.GetOwnedGames(ctx, steamID)
+	if err != nil {
+		log.Printf("export for %s: owned games for names: %v", steamID, err)
+		return
+	}
+	names := make(map[int]string, len(owned))
+	for _, g := range owned {
+		names[g.AppID] = g.Name
+	}
+	for i := range games {
+		if games[i].Name == "" {
+			games[i].Name = names[games[i].AppID]
+		}
+	}
+}
+
+// csvColumns are the CSV export's columns. Imports need appId and accept the
+// others in any order; name and updatedAt are ignored.
+var csvColumns = []string{"appId", "name", "status", "rating", "favorite", "playOrder", "notes", "createdAt", "updatedAt"}
+
+// WriteCSV writes export's games as CSV with a header row.
+func WriteCSV(w io.Writer, export *models.Export) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, g := range export.Games {
+		record := []string{
+			strconv.Itoa(g.AppID),
+			g.Name,
+			g.Status,
+			"",
+			strconv.FormatBool(g.IsFavorite),
+			"",
+			"",
+			g.CreatedAt.Format(time.RFC3339),
+			g.UpdatedAt.Format(time.RFC3339),
+		}
+		if g.Rating != nil {
+			record[3] = strconv.FormatFloat(*g.Rating, 'f', -1, 64)
+		}
+		if g.PlayOrder != nil {
+			record[5] = strconv.Itoa(*g.PlayOrder)
+		}
+		if g.Notes != nil {
+			record[6] = *g.Notes
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportRow is one parsed row of an import: its data, or why it can't be
+// imported. AppID is set whenever the row's app ID could be read.
+type ImportRow struct {
+	Row   int
+	AppID int
+	Data  *models.LocalGameData
+	Err   error
+}
+
+// ErrInvalidImport is returned for import files that can't be read at all.
+var ErrInvalidImport = errors.New("invalid import file")
+
+// ParseJSONImport reads an export's JSON, or just its array of games.
+func ParseJSONImport(r io.Reader) ([]ImportRow, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var games []json.RawMessage
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &games)
+	} else {
+		var export struct {
+			Games []json.RawMessage `json:"games"`
+		}
+		err = json.Unmarshal(body, &export)
+		games = export.Games
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	rows := make([]ImportRow, len(games))
+	for i, raw := range games {
+		rows[i] = parseJSONGame(i+1, raw)
+	}
+	return rows, nil
+}
+
+func parseJSONGame(n int, raw json.RawMessage) ImportRow {
+	var game struct {
+		AppID      int             `json:"appId"`
+		Status     json.RawMessage `json:"status"`
+		Rating     *float64        `json:"rating"`
+		IsFavorite bool            `json:"isFavorite"`
+		PlayOrder  *int            `json:"playOrder"`
+		Notes      *string         `json:"notes"`
+		CreatedAt  *time.Time      `json:"createdAt"`
+	}
+	row := ImportRow{Row: n}
+	if err := json.Unmarshal(raw, &game); err != nil {
+		row.Err = fmt.Errorf("invalid game: %v", err)
+		return row
+	}
+	row.AppID = game.AppID
+
+	data := &models.LocalGameData{
+		AppID:      game.AppID,
+		Rating:     game.Rating,
+		IsFavorite: game.IsFavorite,
+		PlayOrder:  game.PlayOrder,
+		Notes:      game.Notes,
+	}
+	if game.CreatedAt != nil {
+		data.CreatedAt = *game.CreatedAt
+	}
+	// Status may be a name, as exported, or a number, as the API returns it.
+	var status string
+	if len(game.Status) > 0 && json.Unmarshal(game.Status, &status) != nil {
+		status = string(game.Status)
+	}
+	if status != "" {
+		s, err := models.ParseLocalGameStatus(status)
+		if err != nil {
+			row.Err = err
+			return row
+		}
+		data.Status = s
+	}
+	row.Data, row.Err = data, validateImport(data)
+	return row
+}
+
+// ParseCSVImport reads CSV with a header row naming the columns.
+func ParseCSVImport(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["appId"]; !ok {
+		return nil, fmt.Errorf("%w: no appId column", ErrInvalidImport)
+	}
+	// Rows of the wrong length are reported, not fatal.
+	cr.FieldsPerRecord = -1
+
+	var rows []ImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		rows = append(rows, parseCSVGame(len(rows)+1, columns, record))
+	}
+	return rows, nil
+}
+
+func parseCSVGame(n int, columns map[string]int, record []string) ImportRow {
+	row := ImportRow{Row: n}
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	fail := func(format string, args ...interface{}) ImportRow {
+		row.Err = fmt.Errorf(format, args...)
+		return row
+	}
+
+	appID, err := strconv.Atoi(field("appId"))
+	if err != nil {
+		return fail("invalid appId %q", field("appId"))
+	}
+	row.AppID = appID
+	data := &models.LocalGameData{AppID: appID}
+
+	if v := field("status"); v != "" {
+		if data.Status, err = models.ParseLocalGameStatus(v); err != nil {
+			return fail("%v", err)
+		}
+	}
+	if v := field("rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fail("invalid rating %q", v)
+		}
+		data.Rating = &rating
+	}
+	if v := field("favorite"); v != "" {
+		if data.IsFavorite, err = strconv.ParseBool(v); err != nil {
+			return fail("invalid favorite %q", v)
+		}
+	}
+	if v := field("playOrder"); v != "" {
+		playOrder, err := strconv.Atoi(v)
+		if err != nil {
+			return fail("invalid playOrder %q", v)
+		}
+		data.PlayOrder = &playOrder
+	}
+	// CSV can't tell empty notes from none; both import as none.
+	if i, ok := columns["notes"]; ok && i < len(record) && record[i] != "" {
+		notes := record[i]
+		data.Notes = &notes
+	}
+	if v := field("createdAt"); v != "" {
+		if data.CreatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return fail("invalid createdAt %q", v)
+		}
+	}
+	row.Data, row.Err = data, validateImport(data)
+	return row
+}
+
+func validateImport(data *models.LocalGameData) error {
+	if data.AppID <= 0 {
+		return errors.New("appId must be a positive number")
+	}
+	if data.Rating != nil && (math.IsNaN(*data.Rating) || math.IsInf(*data.Rating, 0)) {
+		return errors.New("rating must be a number")
+	}
+	return nil
+}
+
+// Import saves rows as the user's tracker data. Rows that fail to parse, or
+// repeat an earlier row's app ID, are skipped and reported; the rest are
+// imported. In replace mode games the import doesn't list are deleted; a
+// game whose row was skipped counts as listed and is kept. All changes are
+// applied at once, or none are. With dryRun nothing is written and the
+// report says what would change.
+//
+// Imported games keep their createdAt when new; changes are recorded in
+// the game's history like any other save.
+func (t *TransferService) Import(ctx context.Context, steamID string, rows []ImportRow, mode models.ImportMode, dryRun bool) (*models.ImportReport, error) {
+	existing, err := t.store.GetAllGameData(ctx, steamID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{Mode: mode, DryRun: dryRun, Rows: len(rows), Errors: []models.ImportRowError{}}
+	now := t.now()
+	listed := make(map[int]bool, len(rows))
+	var save []*models.LocalGameData
+	for _, row := range rows {
+		if row.Err == nil && listed[row.AppID] {
+			row.Err = errors.New("app ID repeats an earlier row")
+		}
+		if row.AppID > 0 {
+			listed[row.AppID] = true
+		}
+		if row.Err != nil {
+			report.Errors = append(report.Errors, models.ImportRowError{Row: row.Row, AppID: row.AppID, Message: row.Err.Error()})
+			continue
+		}
+
+		old := existing[row.AppID]
+		switch {
+		case old == nil:
+			report.Created++
+		case old.SameValues(row.Data):
+			report.Unchanged++
+			continue
+		default:
+			report.Updated++
+		}
+		if dryRun {
+			continue
+		}
+		data := row.Data
+		if data.CreatedAt.IsZero() {
+			data.CreatedAt = now
+		}
+		data.UpdatedAt = now
+		save = append(save, data)
+	}
+
+	var stale []int
+	if mode == models.ImportReplace {
+		for appID := range existing {
+			if !listed[appID] {
+				stale = append(stale, appID)
+			}
+		}
+		sort.Ints(stale)
+		report.Deleted = len(stale)
+	}
+	if dryRun || (len(save) == 0 && len(stale) == 0) {
+		return report, nil
+	}
+	if err := t.store.ApplyGameData(ctx, steamID, save, stale); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
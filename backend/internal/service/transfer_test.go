@@ -0,0 +1,209 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// libraryNames is a LibraryStore holding only game names.
+type libraryNames map[int]string
+
+func (l libraryNames) GetLibraryEntries(ctx context.Context, steamID string) (map[int]*models.LibraryEntry, error) {
+	entries := make(map[int]*models.LibraryEntry)
+	for appID, name := range l {
+		entries[appID] = &models.LibraryEntry{AppID: appID, Name: name}
+	}
+	return entries, nil
+}
+
+func (l libraryNames) UpdateLibrary(ctx context.Context, steamID string, diff store.LibraryDiff) error {
+	return nil
+}
+
+func (l libraryNames) ListLibraryEvents(ctx context.Context, steamID string, since time.Time) ([]models.LibraryEvent, error) {
+	return nil, nil
+}
+
+func (l libraryNames) IsAppOwned(ctx context.Context, appID int) (bool, error) {
+	_, ok := l[appID]
+	return ok, nil
+}
+
+const transferSteamID = "76561197960287930"
+
+func ptr[T any](v T) *T { return &v }
+
+func TestTransferRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := store.NewMemoryStore()
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	games := []*models.LocalGameData{
+		{AppID: 440, Rating: ptr(8.5), Status: models.StatusPlaying, IsFavorite: true, PlayOrder: ptr(1), Notes: ptr("hats, \"more\" hats\nand a second line")},
+		{AppID: 570, Status: models.StatusDropped},
+		{AppID: 620, Rating: ptr(10.0), Status: models.StatusCompleted, PlayOrder: ptr(2)},
+	}
+	for _, g := range games {
+		g.CreatedAt, g.UpdatedAt = created, created
+		if err := source.SaveGameData(ctx, transferSteamID, g); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	export, err := NewTransferService(source, libraryNames{440: "Team Fortress 2", 620: "Portal 2"}, nil).Export(ctx, transferSteamID)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(export.Games) != 3 || export.Games[0].Name != "Team Fortress 2" || export.Games[1].Name != "" || export.Games[0].Status != "playing" {
+		t.Fatalf("export games = %+v", export.Games)
+	}
+
+	var csvBody, jsonBody bytes.Buffer
+	if err := WriteCSV(&csvBody, export); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if err := json.NewEncoder(&jsonBody).Encode(export); err != nil {
+		t.Fatalf("encoding JSON: %v", err)
+	}
+
+	for format, parse := range map[string]func() ([]ImportRow, error){
+		"csv":  func() ([]ImportRow, error) { return ParseCSVImport(&csvBody) },
+		"json": func() ([]ImportRow, error) { return ParseJSONImport(&jsonBody) },
+	} {
+		rows, err := parse()
+		if err != nil {
+			t.Fatalf("%s: parsing: %v", format, err)
+		}
+		target := store.NewMemoryStore()
+		report, err := NewTransferService(target, libraryNames{}, nil).Import(ctx, transferSteamID, rows, models.ImportMerge, false)
+		if err != nil {
+			t.Fatalf("%s: Import: %v", format, err)
+		}
+		if report.Created != 3 || len(report.Errors) != 0 {
+			t.Errorf("%s: report = %+v, want 3 created", format, report)
+		}
+		for _, want := range games {
+			got, err := target.GetGameData(ctx, transferSteamID, want.AppID)
+			if err != nil || got == nil {
+				t.Fatalf("%s: GetGameData(%d) = %v, %v", format, want.AppID, got, err)
+			}
+			if !got.SameValues(want) || !got.CreatedAt.Equal(created) {
+				t.Errorf("%s: imported %+v, want %+v created %v", format, got, want, created)
+			}
+		}
+	}
+}
+
+func TestTransferImportReport(t *testing.T) {
+	ctx := context.Background()
+	memory := store.NewMemoryStore()
+	now := time.Now()
+	for _, g := range []*models.LocalGameData{
+		{AppID: 10, Rating: ptr(5.0)},
+		{AppID: 20, Rating: ptr(6.0)},
+		{AppID: 30, Rating: ptr(7.0)},
+		{AppID: 40, Rating: ptr(8.0)},
+	} {
+		g.CreatedAt, g.UpdatedAt = now, now
+		if err := memory.SaveGameData(ctx, transferSteamID, g); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	transfer := NewTransferService(memory, libraryNames{}, nil)
+
+	csvImport := strings.Join([]string{
+		"appId,status,rating,notes",
+		"10,none,5,",          // unchanged
+		"20,backlog,6,",       // updated: status
+		"50,playing,,new one", // created
+		"30,bogus,7,",         // error, but 30 is listed so replace keeps it
+		"50,playing,,again",   // error: repeats 50
+		"abc,,,",              // error: no app ID
+	}, "\n")
+	parse := func() []ImportRow {
+		rows, err := ParseCSVImport(strings.NewReader(csvImport))
+		if err != nil {
+			t.Fatalf("ParseCSVImport: %v", err)
+		}
+		return rows
+	}
+
+	dry, err := transfer.Import(ctx, transferSteamID, parse(), models.ImportReplace, true)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if dry.Rows != 6 || dry.Created != 1 || dry.Updated != 1 || dry.Unchanged != 1 || dry.Deleted != 1 || len(dry.Errors) != 3 {
+		t.Errorf("dry run report = %+v", dry)
+	}
+	if all, _ := memory.GetAllGameData(ctx, transferSteamID); len(all) != 4 || all[20].Status != models.StatusNone {
+		t.Fatalf("dry run changed the data: %v", all)
+	}
+	for i, want := range []models.ImportRowError{{Row: 4, AppID: 30}, {Row: 5, AppID: 50}, {Row: 6}} {
+		if got := dry.Errors[i]; got.Row != want.Row || got.AppID != want.AppID || got.Message == "" {
+			t.Errorf("error %d = %+v, want row %d app %d", i, got, want.Row, want.AppID)
+		}
+	}
+
+	report, err := transfer.Import(ctx, transferSteamID, parse(), models.ImportReplace, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.DryRun || report.Created != dry.Created || report.Updated != dry.Updated ||
+		report.Unchanged != dry.Unchanged || report.Deleted != dry.Deleted || len(report.Errors) != len(dry.Errors) {
+		t.Errorf("report = %+v, want the dry run's counts %+v", report, dry)
+	}
+	all, err := memory.GetAllGameData(ctx, transferSteamID)
+	if err != nil {
+		t.Fatalf("GetAllGameData: %v", err)
+	}
+	if len(all) != 4 || all[40] != nil || all[30] == nil || all[20].Status != models.StatusBacklog {
+		t.Errorf("after replace: %v; want 10, 20 (backlog), 30 and 50", all)
+	}
+	if d := all[50]; d == nil || d.Notes == nil || *d.Notes != "new one" || d.Status != models.StatusPlaying {
+		t.Errorf("created game = %+v", d)
+	}
+
+	if _, err := ParseCSVImport(strings.NewReader("name,rating\nx,1")); err == nil {
+		t.Error("CSV without an appId column parsed")
+	}
+	if _, err := ParseJSONImport(strings.NewReader("{not json")); err == nil {
+		t.Error("malformed JSON parsed")
+	}
+}
+
+func TestExportNamesGamesMissingFromLibrary(t *testing.T) {
+	ctx := context.Background()
+	steam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "GetOwnedGames") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"response": {"games": [{"appid": 440, "name": "TF2 on Steam"}, {"appid": 570, "name": "Dota 2"}]}}`))
+	}))
+	defer steam.Close()
+	memory := store.NewMemoryStore()
+	for _, appID := range []int{440, 570, 999} {
+		if err := memory.SaveGameData(ctx, transferSteamID, &models.LocalGameData{AppID: appID}); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	client := NewSteamClient("key", WithBaseURL(steam.URL))
+
+	export, err := NewTransferService(memory, libraryNames{440: "Team Fortress 2"}, client).Export(ctx, transferSteamID)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	// The library's name wins; 999 is neither in the library nor owned.
+	want := map[int]string{440: "Team Fortress 2", 570: "Dota 2", 999: ""}
+	for _, g := range export.Games {
+		if g.Name != want[g.AppID] {
+			t.Errorf("app %d exported as %q, want %q", g.AppID, g.Name, want[g.AppID])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package steamid parses, validates and converts between the SteamID formats
+// used by Steam for individual (user) accounts.
+//
+//	SteamID64   76561197960287930
+//	SteamID3    [U:1:22202]
+//	SteamID2    STEAM_0:0:11101
+//	Account ID  22202
+package steamid
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for strings that are not a SteamID in any supported format.
+var ErrInvalid = errors.New("invalid steam id")
+
+// ID is a SteamID64 of an individual account in the public universe.
+type ID uint64
+
+// base is the SteamID64 of account 0: universe 1 (public), type 1 (individual), instance 1 (desktop).
+const base ID = 76561197960265728
+
+// maxAccountID is the largest 32-bit account number.
+const maxAccountID = 1<<32 - 1
+
+var (
+	steamID2Pattern = regexp.MustCompile(`^STEAM_[0-5]:([01]):(\d{1,10})$`)
+	steamID3Pattern = regexp.MustCompile(`^\[U:1:(\d{1,10})\]$`)
+	vanityPattern   = regexp.MustCompile(`^[A-Za-z0-9_-]{2,32}$`)
+)
+
+// FromAccountID builds the SteamID64 for a 32-bit account ID.
+func FromAccountID(accountID uint32) ID {
+	return base + ID(accountID)
+}
+
+// Parse accepts a SteamID64, SteamID3, SteamID2 or a bare account ID.
+// A bare number is read as a SteamID64 if it is in the individual account
+// range and as an account ID if it fits in 32 bits.
+func Parse(s string) (ID, error) {
+	s = strings.TrimSpace(s)
+
+	if m := steamID2Pattern.FindStringSubmatch(s); m != nil {
+		z, err := strconv.ParseUint(m[2], 10, 31)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		accountID := uint32(z) * 2
+		if m[1] == "1" {
+			accountID++
+		}
+		return valid(FromAccountID(accountID), s)
+	}
+
+	if m := steamID3Pattern.FindStringSubmatch(s); m != nil {
+		accountID, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		return valid(FromAccountID(uint32(accountID)), s)
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	if id := ID(n); id.Valid() {
+		return id, nil
+	}
+	if n <= maxAccountID {
+		return valid(FromAccountID(uint32(n)), s)
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+}
+
+func valid(id ID, input string) (ID, error) {
+	if !id.Valid() {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, input)
+	}
+	return id, nil
+}
+
+// Valid reports whether id is an individual account in the public universe.
+// Account 0 is reserved and never valid.
+func (id ID) Valid() bool {
+	return id > base && id-base <= maxAccountID
+}
+
+// AccountID returns the 32-bit account number.
+func (id ID) AccountID() uint32 {
+	return uint32(id - base)
+}
+
+// String returns the SteamID64 form, which is what the Steam Web API expects.
+func (id ID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// SteamID3 returns the [U:1:account] form.
+func (id ID) SteamID3() string {
+	return fmt.Sprintf("[U:1:%d]", id.AccountID())
+}
+
+// SteamID2 returns the legacy STEAM_0:Y:Z form.
+func (id ID) SteamID2() string {
+	acc := id.AccountID()
+	return fmt.Sprintf("STEAM_0:%d:%d", acc&1, acc>>1)
+}
+
+// IsVanityName reports whether s is syntactically a custom profile name
+// (the part after steamcommunity.com/id/).
+func IsVanityName(s string) bool {
+	return vanityPattern.MatchString(s)
+}
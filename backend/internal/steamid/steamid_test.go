@@ -0,0 +1,128 @@
+package steamid
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  ID
+	}{
+		{"SteamID64", "76561197960287930", 76561197960287930},
+		{"SteamID3", "[U:1:22202]", 76561197960287930},
+		{"SteamID2 even account", "STEAM_0:0:11101", 76561197960287930},
+		{"SteamID2 odd account", "STEAM_0:1:11101", 76561197960287931},
+		{"SteamID2 universe 1", "STEAM_1:0:11101", 76561197960287930},
+		{"bare account ID", "22202", 76561197960287930},
+		{"surrounding space", "  76561197960287930\n", 76561197960287930},
+		{"account 1", "1", 76561197960265729},
+		{"max account SteamID64", "76561202255233023", 76561202255233023},
+		{"max account SteamID3", "[U:1:4294967295]", 76561202255233023},
+		{"max account SteamID2", "STEAM_0:1:2147483647", 76561202255233023},
+		{"max account bare", "4294967295", 76561202255233023},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("%s: Parse(%q) error: %v", tt.name, tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Parse(%q) = %d, want %d", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"vanity name", "gabelogannewell"},
+		{"negative", "-1"},
+		{"account 0 bare", "0"},
+		{"account 0 SteamID64", "76561197960265728"},
+		{"account 0 SteamID3", "[U:1:0]"},
+		{"account 0 SteamID2", "STEAM_0:0:0"},
+		{"past max account SteamID64", "76561202255233024"},
+		{"past max account SteamID3", "[U:1:4294967296]"},
+		{"past max account SteamID2", "STEAM_0:0:2147483648"},
+		{"between account and SteamID64 range", "4294967296"},
+		{"overflows uint64", "18446744073709551616"},
+		{"SteamID3 of another type", "[G:1:22202]"},
+		{"SteamID3 of another universe", "[U:2:22202]"},
+		{"SteamID2 bad Y", "STEAM_0:2:11101"},
+		{"SteamID2 bad universe", "STEAM_6:0:11101"},
+		{"trailing junk", "76561197960287930x"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Parse(%q) = %d, %v; want ErrInvalid", tt.name, tt.input, got, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, accountID := range []uint32{1, 2, 22202, 1<<31 - 1, 1 << 31, 1<<32 - 1} {
+		id := FromAccountID(accountID)
+		if !id.Valid() {
+			t.Fatalf("FromAccountID(%d) = %d, not valid", accountID, id)
+		}
+		if id.AccountID() != accountID {
+			t.Errorf("FromAccountID(%d).AccountID() = %d", accountID, id.AccountID())
+		}
+		for _, s := range []string{id.String(), id.SteamID3(), id.SteamID2()} {
+			got, err := Parse(s)
+			if err != nil || got != id {
+				t.Errorf("Parse(%q) = %d, %v; want %d", s, got, err, id)
+			}
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	id := ID(76561197960287930)
+	if got := id.String(); got != "76561197960287930" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := id.SteamID3(); got != "[U:1:22202]" {
+		t.Errorf("SteamID3() = %q", got)
+	}
+	if got := id.SteamID2(); got != "STEAM_0:0:11101" {
+		t.Errorf("SteamID2() = %q", got)
+	}
+	if got := id.AccountID(); got != 22202 {
+		t.Errorf("AccountID() = %d", got)
+	}
+}
+
+func TestIsVanityName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"gabelogannewell", true},
+		{"ab", true},
+		{"dash-and_underscore", true},
+		{"76561197960287930", true},
+		{strings.Repeat("a", 32), true},
+		{"a", false},
+		{strings.Repeat("a", 33), false},
+		{"", false},
+		{"has space", false},
+		{"dot.name", false},
+		{"slash/name", false},
+		{"héllo", false},
+	}
+	for _, tt := range tests {
+		if got := IsVanityName(tt.input); got != tt.want {
+			t.Errorf("IsVanityName(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,523 @@
+package store
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements Store, HistoryStore, JobStore, ProgressStore and
+// ErasureStore in memory, for tests and demos. Nothing survives a restart.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	users        map[string]models.SteamUser
+	gameData     map[string]map[int]models.LocalGameData
+	history      map[string]map[int][]models.GameDataRevision
+	lastRevision int64
+	jobs         map[string]*models.Job
+	progress     map[string]map[int]models.AchievementProgress
+	erasures     map[string]time.Time
+	now          func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:    make(map[string]models.SteamUser),
+		gameData: make(map[string]map[int]models.LocalGameData),
+		history:  make(map[string]map[int][]models.GameDataRevision),
+		jobs:     make(map[string]*models.Job),
+		progress: make(map[string]map[int]models.AchievementProgress),
+		erasures: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+var (
+	_ Store         = (*MemoryStore)(nil)
+	_ HistoryStore  = (*MemoryStore)(nil)
+	_ JobStore      = (*MemoryStore)(nil)
+	_ ProgressStore = (*MemoryStore)(nil)
+	_ ErasureStore  = (*MemoryStore)(nil)
+)
+
+func (m *MemoryStore) SaveGameData(ctx context.Context, steamID string, data *models.LocalGameData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saveGameData(ctx, steamID, data)
+	return nil
+}
+
+func (m *MemoryStore) DeleteGameData(ctx context.Context, steamID string, appID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteGameData(ctx, steamID, appID)
+	return nil
+}
+
+func (m *MemoryStore) ApplyGameData(ctx context.Context, steamID string, save []*models.LocalGameData, del []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, data := range save {
+		m.saveGameData(ctx, steamID, data)
+	}
+	for _, appID := range del {
+		m.deleteGameData(ctx, steamID, appID)
+	}
+	return nil
+}
+
+// saveGameData upserts data. m.mu must be held for writing.
+func (m *MemoryStore) saveGameData(ctx context.Context, steamID string, data *models.LocalGameData) {
+	games := m.gameData[steamID]
+	if games == nil {
+		games = make(map[int]models.LocalGameData)
+		m.gameData[steamID] = games
+	}
+	saved := *cloneGameData(data)
+	// Like the SQL stores, CreatedAt is only written on insert.
+	old, existed := games[data.AppID]
+	if existed {
+		saved.CreatedAt = old.CreatedAt
+	}
+	games[data.AppID] = saved
+
+	if existed && old.SameValues(&saved) {
+		return
+	}
+	var previous *models.LocalGameData
+	if existed {
+		previous = &old
+	}
+	m.addRevision(ctx, steamID, data.AppID, previous, &saved)
+}
+
+// deleteGameData removes the game's data, if any. m.mu must be held for writing.
+func (m *MemoryStore) deleteGameData(ctx context.Context, steamID string, appID int) {
+	old, ok := m.gameData[steamID][appID]
+	if !ok {
+		return
+	}
+	delete(m.gameData[steamID], appID)
+	m.addRevision(ctx, steamID, appID, &old, nil)
+}
+
+// addRevision records a change. m.mu must be held for writing.
+func (m *MemoryStore) addRevision(ctx context.Context, steamID string, appID int, previous, data *models.LocalGameData) {
+	games := m.history[steamID]
+	if games == nil {
+		games = make(map[int][]models.GameDataRevision)
+		m.history[steamID] = games
+	}
+	m.lastRevision++
+	rev := models.GameDataRevision{
+		Revision:  m.lastRevision,
+		AppID:     appID,
+		ChangedAt: m.now(),
+		Client:    ClientInfoFrom(ctx),
+	}
+	if previous != nil {
+		rev.Previous = cloneGameData(previous)
+	}
+	if data != nil {
+		rev.Data = cloneGameData(data)
+	}
+	games[appID] = append(games[appID], rev)
+}
+
+func (m *MemoryStore) ListGameDataHistory(ctx context.Context, steamID string, appID int) ([]models.GameDataRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := m.history[steamID][appID]
+	results := make([]models.GameDataRevision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		results = append(results, cloneRevision(revisions[i]))
+	}
+	return results, nil
+}
+
+func (m *MemoryStore) GetGameDataRevision(ctx context.Context, steamID string, appID int, revision int64) (*models.GameDataRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rev := range m.history[steamID][appID] {
+		if rev.Revision == revision {
+			c := cloneRevision(rev)
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func cloneRevision(rev models.GameDataRevision) models.GameDataRevision {
+	if rev.Previous != nil {
+		rev.Previous = cloneGameData(rev.Previous)
+	}
+	if rev.Data != nil {
+		rev.Data = cloneGameData(rev.Data)
+	}
+	return rev
+}
+
+func (m *MemoryStore) GetGameData(ctx context.Context, steamID string, appID int) (*models.LocalGameData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.gameData[steamID][appID]
+	if !ok {
+		return nil, nil // Not found
+	}
+	return cloneGameData(&data), nil
+}
+
+func (m *MemoryStore) GetAllGameData(ctx context.Context, steamID string) (map[int]*models.LocalGameData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[int]*models.LocalGameData, len(m.gameData[steamID]))
+	for appID, data := range m.gameData[steamID] {
+		results[appID] = cloneGameData(&data)
+	}
+	return results, nil
+}
+
+func (m *MemoryStore) QueryGameData(ctx context.Context, steamID string, q GameDataQuery) ([]*models.LocalGameData, error) {
+	m.mu.RLock()
+	results := []*models.LocalGameData{}
+	for _, data := range m.gameData[steamID] {
+		if matchesGameDataQuery(&data, q) {
+			results = append(results, cloneGameData(&data))
+		}
+	}
+	m.mu.RUnlock()
+
+	sortGameData(results, q.Sort, q.Desc)
+	if q.Limit > 0 {
+		start := min(q.Offset, len(results))
+		results = results[start:min(start+q.Limit, len(results))]
+	}
+	return results, nil
+}
+
+func matchesGameDataQuery(data *models.LocalGameData, q GameDataQuery) bool {
+	if q.Status != nil && data.Status != *q.Status {
+		return false
+	}
+	// As in SQL, a rating filter never matches games without a rating.
+	if q.MinRating != nil && (data.Rating == nil || *data.Rating < *q.MinRating) {
+		return false
+	}
+	if q.MaxRating != nil && (data.Rating == nil || *data.Rating > *q.MaxRating) {
+		return false
+	}
+	if q.Favorite != nil && data.IsFavorite != *q.Favorite {
+		return false
+	}
+	return true
+}
+
+// sortGameData orders results the way the SQL stores do: missing values
+// last, ties broken by app ID.
+func sortGameData(results []*models.LocalGameData, by GameDataSort, desc bool) {
+	// key returns the sort value and whether it is set.
+	key := func(d *models.LocalGameData) (float64, bool) {
+		switch by {
+		case SortByRating:
+			if d.Rating == nil {
+				return 0, false
+			}
+			return *d.Rating, true
+		case SortByPlayOrder:
+			if d.PlayOrder == nil {
+				return 0, false
+			}
+			return float64(*d.PlayOrder), true
+		case SortByCreated:
+			return float64(d.CreatedAt.Unix()), true
+		case SortByUpdated:
+			return float64(d.UpdatedAt.Unix()), true
+		default:
+			return float64(d.AppID), true
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, aSet := key(results[i])
+		b, bSet := key(results[j])
+		switch {
+		case aSet != bSet:
+			return aSet
+		case a != b:
+			return (a < b) != desc
+		}
+		return results[i].AppID < results[j].AppID
+	})
+}
+
+// cloneGameData copies data, including the values behind its pointers, so
+// callers can't change what the store holds.
+func cloneGameData(data *models.LocalGameData) *models.LocalGameData {
+	c := *data
+	if data.Rating != nil {
+		rating := *data.Rating
+		c.Rating = &rating
+	}
+	if data.Notes != nil {
+		notes := *data.Notes
+		c.Notes = &notes
+	}
+	if data.PlayOrder != nil {
+		playOrder := *data.PlayOrder
+		c.PlayOrder = &playOrder
+	}
+	return &c
+}
+
+func (m *MemoryStore) SaveUser(ctx context.Context, user *models.SteamUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[user.SteamID]; !ok && registeredUserOnly(ctx) {
+		return ErrUnknownUser
+	}
+	m.users[user.SteamID] = *user
+	return nil
+}
+
+func (m *MemoryStore) GetUser(ctx context.Context, steamID string) (*models.SteamUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[steamID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (m *MemoryStore) ListUserIDs(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []string
+	for id := range m.users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (m *MemoryStore) EraseUser(ctx context.Context, steamID string, cache CacheFilter) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := map[string]int64{
+		"users":                0,
+		"jobs":                 0,
+		"user_game_data":       int64(len(m.gameData[steamID])),
+		"game_data_history":    0,
+		"achievement_progress": int64(len(m.progress[steamID])),
+	}
+	if _, ok := m.users[steamID]; ok {
+		deleted["users"] = 1
+	}
+	for id, job := range m.jobs {
+		if job.SteamID == steamID {
+			delete(m.jobs, id)
+			deleted["jobs"]++
+		}
+	}
+	for _, revisions := range m.history[steamID] {
+		deleted["game_data_history"] += int64(len(revisions))
+	}
+	delete(m.users, steamID)
+	delete(m.gameData, steamID)
+	delete(m.history, steamID)
+	delete(m.progress, steamID)
+	m.erasures[steamID] = m.now().Truncate(time.Second)
+	return deleted, nil
+}
+
+func (m *MemoryStore) ErasedAt(ctx context.Context, steamID string) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.erasures[steamID], nil
+}
+
+func (m *MemoryStore) SaveAchievementProgress(ctx context.Context, steamID string, progress *models.AchievementProgress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lease, ok := jobLeaseFrom(ctx); ok {
+		if job, ok := m.jobs[lease.jobID]; !ok || job.LeaseID != lease.leaseID {
+			return ErrLeaseLost
+		}
+	}
+	if m.progress[steamID] == nil {
+		m.progress[steamID] = make(map[int]models.AchievementProgress)
+	}
+	m.progress[steamID][progress.AppID] = *progress
+	return nil
+}
+
+func (m *MemoryStore) GetAchievementProgress(ctx context.Context, steamID string) ([]models.AchievementProgress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.AchievementProgress, 0, len(m.progress[steamID]))
+	for _, p := range m.progress[steamID] {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AppID < result[j].AppID })
+	return result, nil
+}
+
+func (m *MemoryStore) CreateJob(ctx context.Context, job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[job.SteamID]; !ok && registeredUserOnly(ctx) {
+		return ErrUnknownUser
+	}
+	if _, ok := m.jobs[job.ID]; ok {
+		return fmt.Errorf("job %s already exists", job.ID)
+	}
+	if !job.Finished() {
+		for _, other := range m.jobs {
+			if other.Type == job.Type && other.SteamID == job.SteamID && !other.Finished() {
+				return ErrActiveJobExists
+			}
+		}
+	}
+	m.jobs[job.ID] = cloneJob(job)
+	return nil
+}
+
+func (m *MemoryStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return cloneJob(job), nil
+}
+
+func (m *MemoryStore) FindActiveJob(ctx context.Context, jobType, steamID string) (*models.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *models.Job
+	for _, job := range m.jobs {
+		if job.Type != jobType || job.SteamID != steamID || job.Finished() {
+			continue
+		}
+		if found == nil || job.CreatedAt.Before(found.CreatedAt) {
+			found = job
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return cloneJob(found), nil
+}
+
+func (m *MemoryStore) LeaseJob(ctx context.Context, now time.Time, leaseID string, leasedUntil time.Time) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *models.Job
+	for _, job := range m.jobs {
+		due := job.Status == models.JobQueued && !job.RunAt.After(now)
+		expired := job.Status == models.JobRunning && !job.LeasedUntil.After(now)
+		if (due || expired) && (next == nil || job.RunAt.Before(next.RunAt)) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status = models.JobRunning
+	next.LeaseID = leaseID
+	next.LeasedUntil = leasedUntil
+	next.Attempts++
+	next.UpdatedAt = now
+	return cloneJob(next), nil
+}
+
+func (m *MemoryStore) RenewJobLease(ctx context.Context, id, leaseID string, leasedUntil time.Time, progress json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.leasedJob(id, leaseID)
+	if err != nil {
+		return err
+	}
+	job.LeasedUntil = leasedUntil
+	if progress != nil {
+		job.Progress = append(json.RawMessage(nil), progress...)
+	}
+	job.UpdatedAt = m.now()
+	return nil
+}
+
+func (m *MemoryStore) FinishJobAttempt(ctx context.Context, job *models.Job, leaseID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.leasedJob(job.ID, leaseID)
+	if err != nil {
+		return err
+	}
+	finished := cloneJob(job)
+	finished.LeaseID = ""
+	finished.LeasedUntil = time.Time{}
+	finished.Payload = stored.Payload
+	m.jobs[job.ID] = finished
+	return nil
+}
+
+func (m *MemoryStore) PurgeJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, job := range m.jobs {
+		if job.Finished() && job.FinishedAt != nil && job.FinishedAt.Before(finishedBefore) {
+			delete(m.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// leasedJob returns the running job held under leaseID. m.mu must be held.
+func (m *MemoryStore) leasedJob(id, leaseID string) (*models.Job, error) {
+	job, ok := m.jobs[id]
+	if !ok || job.Status != models.JobRunning || job.LeaseID != leaseID {
+		return nil, ErrLeaseLost
+	}
+	return job, nil
+}
+
+func cloneJob(job *models.Job) *models.Job {
+	c := *job
+	c.Payload = append(json.RawMessage(nil), job.Payload...)
+	c.Progress = append(json.RawMessage(nil), job.Progress...)
+	c.Result = append(json.RawMessage(nil), job.Result...)
+	if job.FinishedAt != nil {
+		finished := *job.FinishedAt
+		c.FinishedAt = &finished
+	}
+	return &c
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+package store_test
+
+import (
+	"backend/internal/store"
+	"backend/internal/store/storetest"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is one schema change, loaded from a pair of files named
+// NNNN_name.up.sql and NNNN_name.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back migrations, recording them in the
+// schema_migrations table. Each migration runs in its own transaction.
+type Migrator struct {
+	db         *sqlDB
+	migrations []Migration
+}
+
+func newMigrator(db *sqlDB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.up.sql or .down.sql", name)
+		}
+		num, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", name)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at BIGINT
+	);
+	`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at int64
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = time.Unix(at, 0)
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every known migration, oldest first.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, len(m.migrations))
+	for i, mig := range m.migrations {
+		status[i] = MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status[i].AppliedAt = &at
+		}
+	}
+	return status, nil
+}
+
+// Up applies all pending migrations in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.appliedKnown(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return m.upTo(ctx, applied, math.MaxInt)
+}
+
+// To migrates up or down until exactly the migrations up to and including
+// version are applied, and returns how many ran. Version 0 rolls back
+// everything.
+func (m *Migrator) To(ctx context.Context, version int) (int, error) {
+	if version != 0 && m.find(version) < 0 {
+		return 0, fmt.Errorf("unknown migration %d", version)
+	}
+	applied, err := m.appliedKnown(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := m.upTo(ctx, applied, version)
+	if err != nil {
+		return n, err
+	}
+	down, err := m.downTo(ctx, applied, version, len(m.migrations))
+	return n + down, err
+}
+
+// appliedKnown returns the applied migrations, refusing a database with
+// migrations this build does not know, such as one migrated by a newer build.
+func (m *Migrator) appliedKnown(ctx context.Context) (map[int]time.Time, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if m.find(version) < 0 {
+			return nil, fmt.Errorf("database has migration %d, which this build does not know; refusing to migrate", version)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) upTo(ctx context.Context, applied map[int]time.Time, version int) (int, error) {
+	n := 0
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		ran, err := m.inTx(ctx, mig.Version, true, mig.Up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().Unix())
+		if err != nil {
+			return n, fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Down rolls back the latest steps applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return m.downTo(ctx, applied, 0, steps)
+}
+
+// downTo rolls back at most steps applied migrations newer than version, newest first.
+func (m *Migrator) downTo(ctx context.Context, applied map[int]time.Time, version, steps int) (int, error) {
+	n := 0
+	for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= version {
+			break
+		}
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		ran, err := m.inTx(ctx, mig.Version, false, mig.Down,
+			`DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+		if err != nil {
+			return n, fmt.Errorf("rolling back migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// inTx runs script and then the bookkeeping statement in one transaction.
+// The transaction first takes the dialect's migration lock and re-checks the
+// version, so that when two servers start at once only one applies (up) or
+// rolls back (down) the migration. It reports whether the script ran.
+func (m *Migrator) inTx(ctx context.Context, version int, up bool, script, record string, args ...interface{}) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if lock := m.db.dialect.migrationLock; lock != "" {
+		if _, err := tx.ExecContext(ctx, lock); err != nil {
+			return false, err
+		}
+	}
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if (count > 0) == up {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDialectMigrationsMatch(t *testing.T) {
+	sqlite, err := loadMigrations(migrationFiles, sqliteDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading SQLite migrations: %v", err)
+	}
+	postgres, err := loadMigrations(migrationFiles, postgresDialect.migrations)
+	if err != nil {
+		t.Fatalf("loading PostgreSQL migrations: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("SQLite has %d migrations and PostgreSQL %d; want the same set", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != i+1 {
+			t.Errorf("migration %d has version %d; want versions without gaps", i+1, sqlite[i].Version)
+		}
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d is %d_%s for SQLite but %d_%s for PostgreSQL",
+				i+1, sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+	// Each dialect gets its own SQL, not a shared file.
+	if strings.Contains(sqlite[0].Up, "BIGSERIAL") || sqlite[0].Up == postgres[0].Up {
+		t.Error("SQLite baseline looks like the PostgreSQL one")
+	}
+}
+
+func TestLoadMigrationsRejectsBadSets(t *testing.T) {
+	sets := map[string]fstest.MapFS{
+		"missing down": {
+			"m/0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		},
+		"two names": {
+			"m/0001_init.up.sql":    {Data: []byte("CREATE TABLE a (id INTEGER);")},
+			"m/0001_other.down.sql": {Data: []byte("DROP TABLE a;")},
+		},
+		"bad version": {
+			"m/init.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+			"m/init.down.sql": {Data: []byte("DROP TABLE a;")},
+		},
+		"bad direction": {
+			"m/0001_init.sideways.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range sets {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: loadMigrations succeeded", name)
+		}
+	}
+}
+
+// Two servers starting at once both see a migration as pending; only the
+// one that gets the lock first may apply it.
+func TestMigratorSkipsMigrationsAppliedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "steam_data.db")
+	open := func() *Migrator {
+		s, err := OpenSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("OpenSQLiteStore: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		m, err := s.Migrator()
+		if err != nil {
+			t.Fatalf("Migrator: %v", err)
+		}
+		return m
+	}
+	a, b := open(), open()
+
+	seenByB, err := b.appliedKnown(ctx)
+	if err != nil {
+		t.Fatalf("appliedKnown: %v", err)
+	}
+	if n, err := a.Up(ctx); err != nil || n != len(a.migrations) {
+		t.Fatalf("a.Up = %d, %v; want all %d", n, err, len(a.migrations))
+	}
+	// b carries on from its stale view.
+	if n, err := b.upTo(ctx, seenByB, len(b.migrations)); err != nil || n != 0 {
+		t.Errorf("b applying its pending migrations = %d, %v; want none run", n, err)
+	}
+
+	// Likewise when both roll back the latest migration.
+	seenByB, err = b.appliedKnown(ctx)
+	if err != nil {
+		t.Fatalf("appliedKnown: %v", err)
+	}
+	if n, err := a.Down(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("a.Down = %d, %v; want 1", n, err)
+	}
+	if n, err := b.downTo(ctx, seenByB, len(b.migrations)-1, 1); err != nil || n != 0 {
+		t.Errorf("b rolling back = %d, %v; want none run", n, err)
+	}
+}
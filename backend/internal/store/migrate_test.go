@@ -0,0 +1,204 @@
+package store_test
+
+import (
+	"backend/internal/store"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openAt returns a migrated SQLite store rolled back to version, along with a
+// raw handle on the same file.
+func openAt(t *testing.T, version int) (*store.SQLiteStore, *store.Migrator, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "steam_data.db")
+	s, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator: %v", err)
+	}
+	status, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if steps := status[len(status)-1].Version - version; steps > 0 {
+		if _, err := m.Down(context.Background(), steps); err != nil {
+			t.Fatalf("Down(%d): %v", steps, err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return s, m, db
+}
+
+func appliedVersions(t *testing.T, m *store.Migrator) map[int]bool {
+	t.Helper()
+	status, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	applied := make(map[int]bool)
+	for _, st := range status {
+		if st.AppliedAt != nil {
+			applied[st.Version] = true
+		}
+	}
+	return applied
+}
+
+func TestNormalizeGameDataRefusesInvalidJSON(t *testing.T) {
+	ctx := context.Background()
+	s, m, db := openAt(t, 7)
+
+	_, err := db.Exec(`INSERT INTO user_game_data (steam_id, app_id, data) VALUES
+		('76561197960287930', 440, '{"appId": 440, "rating": 8, "notes": "hats"}'),
+		('76561197960287930', 570, '{"appId": 570, "notes": "truncated')`)
+	if err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Fatalf("Up error = %v, want the invalid row reported", err)
+	}
+	if applied := appliedVersions(t, m); applied[8] {
+		t.Fatal("migration 8 recorded as applied after failing")
+	}
+	var data string
+	if err := db.QueryRow(`SELECT data FROM user_game_data WHERE app_id = 570`).Scan(&data); err != nil {
+		t.Fatalf("invalid row after failed migration: %v", err)
+	}
+	if !strings.Contains(data, "truncated") {
+		t.Errorf("invalid row's data = %q, want it untouched", data)
+	}
+
+	if _, err := db.Exec(`UPDATE user_game_data SET data = '{"appId": 570, "notes": "fixed"}' WHERE app_id = 570`); err != nil {
+		t.Fatalf("fixing row: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after fixing: %v", err)
+	}
+	for appID, notes := range map[int]string{440: "hats", 570: "fixed"} {
+		game, err := s.GetGameData(ctx, "76561197960287930", appID)
+		if err != nil || game == nil || game.Notes == nil || *game.Notes != notes {
+			t.Errorf("GetGameData(%d) = %+v, %v; want notes %q", appID, game, err, notes)
+		}
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n); err != nil {
+		t.Fatalf("looking up table %s: %v", name, err)
+	}
+	return n > 0
+}
+
+func TestMigratorToVersion(t *testing.T) {
+	ctx := context.Background()
+	_, m, db := openAt(t, 10)
+
+	steps := []struct {
+		version int
+		ran     int
+		jobs    bool
+	}{
+		{version: 5, ran: 5, jobs: false},
+		{version: 5, ran: 0, jobs: false},
+		{version: 8, ran: 3, jobs: true},
+		{version: 0, ran: 8, jobs: false},
+		{version: 10, ran: 10, jobs: true},
+	}
+	for _, step := range steps {
+		n, err := m.To(ctx, step.version)
+		if err != nil {
+			t.Fatalf("To(%d): %v", step.version, err)
+		}
+		if n != step.ran {
+			t.Errorf("To(%d) ran %d migrations, want %d", step.version, n, step.ran)
+		}
+		applied := appliedVersions(t, m)
+		if len(applied) != step.version {
+			t.Errorf("after To(%d), applied = %v", step.version, applied)
+		}
+		for v := 1; v <= step.version; v++ {
+			if !applied[v] {
+				t.Errorf("after To(%d), migration %d not applied", step.version, v)
+			}
+		}
+		if got := tableExists(t, db, "jobs"); got != step.jobs {
+			t.Errorf("after To(%d), jobs table exists = %v, want %v", step.version, got, step.jobs)
+		}
+	}
+
+	if _, err := m.To(ctx, 99); err == nil {
+		t.Error("To(99) succeeded, want an unknown migration error")
+	}
+}
+
+func TestMigratorDownSteps(t *testing.T) {
+	ctx := context.Background()
+	_, m, _ := openAt(t, 12)
+
+	if n, err := m.Down(ctx, 3); err != nil || n != 3 {
+		t.Fatalf("Down(3) = %d, %v; want 3", n, err)
+	}
+	applied := appliedVersions(t, m)
+	if len(applied) != 9 || applied[10] {
+		t.Errorf("after Down(3), applied = %v, want 1 to 9", applied)
+	}
+	if n, err := m.Up(ctx); err != nil || n != 3 {
+		t.Errorf("Up = %d, %v; want 3", n, err)
+	}
+}
+
+func TestMigratorRefusesUnknownVersions(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "steam_data.db")
+	s, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	m, err := s.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator: %v", err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	// As if a newer build had migrated the database further.
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (13, 'from_the_future', 0)`); err != nil {
+		t.Fatalf("recording migration 13: %v", err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "does not know") {
+		t.Errorf("Up error = %v, want migration 13 refused", err)
+	}
+	if _, err := m.To(ctx, 12); err == nil || !strings.Contains(err.Error(), "does not know") {
+		t.Errorf("To error = %v, want migration 13 refused", err)
+	}
+	if applied := appliedVersions(t, m); applied[12] {
+		t.Error("migration 12 applied despite the unknown version")
+	}
+	s.Close()
+
+	if s, err := store.NewSQLiteStore(path); err == nil {
+		s.Close()
+		t.Error("NewSQLiteStore opened a database with an unknown migration")
+	}
+}
@@ -0,0 +1,13 @@
+-- The PostgreSQL schema mirrors migrations/sqlite version for version, so
+-- both databases report the same migration history.
+CREATE TABLE IF NOT EXISTS user_game_data (
+	steam_id TEXT,
+	app_id INTEGER,
+	data TEXT,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	steam_id TEXT PRIMARY KEY,
+	data TEXT
+);
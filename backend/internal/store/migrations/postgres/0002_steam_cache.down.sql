@@ -0,0 +1 @@
+DROP TABLE IF EXISTS steam_cache;
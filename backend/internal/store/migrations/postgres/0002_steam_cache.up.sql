@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS steam_cache (
+	cache_key TEXT PRIMARY KEY,
+	value BYTEA,
+	expires_at BIGINT
+);
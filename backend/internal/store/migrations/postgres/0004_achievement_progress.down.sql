@@ -0,0 +1 @@
+DROP TABLE IF EXISTS achievement_progress;
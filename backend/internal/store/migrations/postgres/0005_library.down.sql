@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS library_events;
+DROP TABLE IF EXISTS library;
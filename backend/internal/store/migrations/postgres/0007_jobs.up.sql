@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	type TEXT,
+	steam_id TEXT,
+	status TEXT,
+	payload TEXT,
+	progress TEXT,
+	result TEXT,
+	error TEXT,
+	attempts INTEGER,
+	max_attempts INTEGER,
+	run_at BIGINT,
+	lease_id TEXT,
+	leased_until BIGINT,
+	created_at BIGINT,
+	updated_at BIGINT,
+	finished_at BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_type_steam_id ON jobs (type, steam_id);
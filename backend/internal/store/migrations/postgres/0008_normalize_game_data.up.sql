@@ -0,0 +1,40 @@
+-- Move LocalGameData out of the JSON blob into columns that can be filtered,
+-- sorted and indexed. Notes move to their own table.
+ALTER TABLE user_game_data
+	ADD COLUMN status INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN rating DOUBLE PRECISION,
+	ADD COLUMN is_favorite BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN play_order INTEGER,
+	ADD COLUMN created_at BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS game_notes (
+	steam_id TEXT,
+	app_id INTEGER,
+	notes TEXT,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+UPDATE user_game_data SET
+	status = COALESCE((data::jsonb ->> 'status')::integer, 0),
+	rating = (data::jsonb ->> 'rating')::double precision,
+	is_favorite = COALESCE((data::jsonb ->> 'isFavorite')::boolean, FALSE),
+	play_order = (data::jsonb ->> 'playOrder')::integer
+WHERE data IS NOT NULL;
+
+-- When rows were created or last changed was never recorded.
+UPDATE user_game_data SET
+	created_at = EXTRACT(EPOCH FROM now())::bigint,
+	updated_at = EXTRACT(EPOCH FROM now())::bigint;
+
+INSERT INTO game_notes (steam_id, app_id, notes)
+SELECT steam_id, app_id, data::jsonb ->> 'notes' FROM user_game_data
+WHERE data IS NOT NULL AND data::jsonb ->> 'notes' IS NOT NULL;
+
+ALTER TABLE user_game_data DROP COLUMN data;
+
+CREATE INDEX IF NOT EXISTS idx_user_game_data_status ON user_game_data (steam_id, status);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_rating ON user_game_data (steam_id, rating);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_favorite ON user_game_data (steam_id, is_favorite);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_play_order ON user_game_data (steam_id, play_order);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_updated_at ON user_game_data (steam_id, updated_at);
@@ -0,0 +1,15 @@
+-- Append-only record of changes to user_game_data. previous_data and
+-- new_data are LocalGameData JSON; NULL when the row was created or deleted.
+CREATE TABLE IF NOT EXISTS game_data_history (
+	id BIGSERIAL PRIMARY KEY,
+	steam_id TEXT,
+	app_id INTEGER,
+	previous_data TEXT,
+	new_data TEXT,
+	changed_at BIGINT,
+	client_ip TEXT,
+	user_agent TEXT,
+	client TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_data_history_game ON game_data_history (steam_id, app_id, id);
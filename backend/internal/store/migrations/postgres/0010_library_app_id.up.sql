@@ -0,0 +1,2 @@
+-- Lets the catalog ask whether anyone owns an app without scanning every library.
+CREATE INDEX IF NOT EXISTS idx_library_app_id ON library (app_id);
@@ -0,0 +1,11 @@
+-- Only one queued or running job per type and user. Older duplicates left by
+-- concurrent enqueues are retired first, keeping the earliest.
+UPDATE jobs SET status = 'dead', error = 'duplicate of an earlier active job', finished_at = updated_at
+WHERE status IN ('queued', 'running') AND EXISTS (
+	SELECT 1 FROM jobs AS earlier
+	WHERE earlier.type = jobs.type AND earlier.steam_id = jobs.steam_id
+		AND earlier.status IN ('queued', 'running')
+		AND (earlier.created_at < jobs.created_at OR (earlier.created_at = jobs.created_at AND earlier.id < jobs.id))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_active ON jobs (type, steam_id) WHERE status IN ('queued', 'running');
@@ -0,0 +1,13 @@
+-- Tables that existed before versioned migrations. IF NOT EXISTS lets
+-- databases created by the old createTable adopt the migration history.
+CREATE TABLE IF NOT EXISTS user_game_data (
+	steam_id TEXT,
+	app_id INTEGER,
+	data TEXT,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	steam_id TEXT PRIMARY KEY,
+	data TEXT
+);
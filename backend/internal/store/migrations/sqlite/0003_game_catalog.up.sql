@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS game_catalog (
+	app_id INTEGER PRIMARY KEY,
+	data TEXT,
+	next_refresh INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_catalog_next_refresh ON game_catalog (next_refresh);
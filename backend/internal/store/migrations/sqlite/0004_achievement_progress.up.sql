@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS achievement_progress (
+	steam_id TEXT,
+	app_id INTEGER,
+	unlocked INTEGER,
+	total INTEGER,
+	last_unlock INTEGER,
+	updated_at INTEGER,
+	PRIMARY KEY (steam_id, app_id)
+);
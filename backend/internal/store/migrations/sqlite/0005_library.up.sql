@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS library (
+	steam_id TEXT,
+	app_id INTEGER,
+	name TEXT,
+	playtime_forever INTEGER,
+	first_seen INTEGER,
+	last_seen INTEGER,
+	removed INTEGER,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+CREATE TABLE IF NOT EXISTS library_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	steam_id TEXT,
+	app_id INTEGER,
+	name TEXT,
+	type TEXT,
+	at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_events_steam_id_at ON library_events (steam_id, at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS playtime_deltas;
+DROP TABLE IF EXISTS playtime_totals;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS playtime_totals (
+	steam_id TEXT,
+	app_id INTEGER,
+	playtime_forever INTEGER,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+CREATE TABLE IF NOT EXISTS playtime_deltas (
+	steam_id TEXT,
+	app_id INTEGER,
+	day INTEGER,
+	minutes INTEGER,
+	PRIMARY KEY (steam_id, day, app_id)
+);
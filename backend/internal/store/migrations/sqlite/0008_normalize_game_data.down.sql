@@ -0,0 +1,25 @@
+DROP INDEX IF EXISTS idx_user_game_data_status;
+DROP INDEX IF EXISTS idx_user_game_data_rating;
+DROP INDEX IF EXISTS idx_user_game_data_favorite;
+DROP INDEX IF EXISTS idx_user_game_data_play_order;
+DROP INDEX IF EXISTS idx_user_game_data_updated_at;
+
+ALTER TABLE user_game_data ADD COLUMN data TEXT;
+
+UPDATE user_game_data SET data = json_object(
+	'appId', app_id,
+	'rating', rating,
+	'notes', (SELECT notes FROM game_notes n WHERE n.steam_id = user_game_data.steam_id AND n.app_id = user_game_data.app_id),
+	'status', status,
+	'isFavorite', json(CASE WHEN is_favorite THEN 'true' ELSE 'false' END),
+	'playOrder', play_order
+);
+
+DROP TABLE IF EXISTS game_notes;
+
+ALTER TABLE user_game_data DROP COLUMN status;
+ALTER TABLE user_game_data DROP COLUMN rating;
+ALTER TABLE user_game_data DROP COLUMN is_favorite;
+ALTER TABLE user_game_data DROP COLUMN play_order;
+ALTER TABLE user_game_data DROP COLUMN created_at;
+ALTER TABLE user_game_data DROP COLUMN updated_at;
@@ -0,0 +1,52 @@
+-- Move LocalGameData out of the JSON blob into columns that can be filtered,
+-- sorted and indexed. Notes move to their own table.
+
+-- Rows whose data isn't JSON can't be moved, and dropping the column would
+-- lose them, notes and all. Abort instead, as the cast does on PostgreSQL,
+-- so they can be fixed or removed by hand first.
+CREATE TEMP TABLE migration_0008_check (steam_id TEXT, app_id INTEGER);
+CREATE TEMP TRIGGER migration_0008_invalid_json BEFORE INSERT ON migration_0008_check
+BEGIN
+	SELECT RAISE(ABORT, 'user_game_data has rows whose data is not valid JSON; fix or delete them and migrate again');
+END;
+INSERT INTO migration_0008_check (steam_id, app_id)
+SELECT steam_id, app_id FROM user_game_data WHERE data IS NOT NULL AND NOT json_valid(data) LIMIT 1;
+DROP TABLE migration_0008_check;
+
+ALTER TABLE user_game_data ADD COLUMN status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_game_data ADD COLUMN rating REAL;
+ALTER TABLE user_game_data ADD COLUMN is_favorite INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_game_data ADD COLUMN play_order INTEGER;
+ALTER TABLE user_game_data ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_game_data ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS game_notes (
+	steam_id TEXT,
+	app_id INTEGER,
+	notes TEXT,
+	PRIMARY KEY (steam_id, app_id)
+);
+
+UPDATE user_game_data SET
+	status = COALESCE(json_extract(data, '$.status'), 0),
+	rating = json_extract(data, '$.rating'),
+	is_favorite = COALESCE(json_extract(data, '$.isFavorite'), 0),
+	play_order = json_extract(data, '$.playOrder')
+WHERE json_valid(data);
+
+-- When rows were created or last changed was never recorded.
+UPDATE user_game_data SET
+	created_at = CAST(strftime('%s', 'now') AS INTEGER),
+	updated_at = CAST(strftime('%s', 'now') AS INTEGER);
+
+INSERT INTO game_notes (steam_id, app_id, notes)
+SELECT steam_id, app_id, json_extract(data, '$.notes') FROM user_game_data
+WHERE json_valid(data) AND json_extract(data, '$.notes') IS NOT NULL;
+
+ALTER TABLE user_game_data DROP COLUMN data;
+
+CREATE INDEX IF NOT EXISTS idx_user_game_data_status ON user_game_data (steam_id, status);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_rating ON user_game_data (steam_id, rating);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_favorite ON user_game_data (steam_id, is_favorite);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_play_order ON user_game_data (steam_id, play_order);
+CREATE INDEX IF NOT EXISTS idx_user_game_data_updated_at ON user_game_data (steam_id, updated_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_library_app_id;
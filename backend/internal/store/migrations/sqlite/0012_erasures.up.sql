@@ -0,0 +1,5 @@
+-- When each user was last erased, so sessions issued before then are refused.
+CREATE TABLE IF NOT EXISTS erasures (
+	steam_id TEXT PRIMARY KEY,
+	erased_at INTEGER
+);
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+var postgresDialect = dialect{
+	numbered:   true,
+	skipLocked: " FOR UPDATE SKIP LOCKED",
+	lockRows:   true,
+	// Held until the migration's transaction ends. The key is arbitrary but
+	// must be the same for every server sharing the database.
+	migrationLock: "SELECT pg_advisory_xact_lock(727368)",
+	// Row locks alone would miss games another refresh is inserting.
+	libraryLock: "SELECT pg_advisory_xact_lock(hashtext('library'), hashtext(?))",
+	migrations:  "migrations/postgres",
+}
+
+// PostgresStore keeps everything in PostgreSQL, so that several servers can
+// share one database.
+type PostgresStore struct {
+	*sqlStore
+}
+
+// NewPostgresStore connects to the database at url (a postgres:// URL or
+// key=value connection string) and applies any pending migrations.
+func NewPostgresStore(url string) (*PostgresStore, error) {
+	s, err := OpenPostgresStore(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.migrate(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenPostgresStore connects to the database at url without migrating it.
+func OpenPostgresStore(url string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresStore{newSQLStore(db, postgresDialect)}, nil
+}
@@ -0,0 +1,1276 @@
+package store
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlStore implements the store interfaces on database/sql. Queries are
+// written with ? placeholders and rebound for the dialect.
+type sqlStore struct {
+	db      *sqlDB
+	dialect dialect
+}
+
+// dialect holds what differs between the supported databases.
+type dialect struct {
+	// numbered switches placeholders from ? to $1, $2, ...
+	numbered bool
+	// skipLocked is appended to queries that pick a row to claim.
+	skipLocked string
+	// lockRows locks rows a transaction reads before changing them. SQLite
+	// doesn't need it: its transactions take the database's write lock.
+	lockRows bool
+	// migrationLock runs at the start of each migration's transaction so
+	// that concurrently starting servers migrate one at a time.
+	migrationLock string
+	// libraryLock runs, with the SteamID as its parameter, at the start of a
+	// library update so that updates for one user run one at a time. SQLite
+	// doesn't need it for the same reason as lockRows.
+	libraryLock string
+	// migrations is the directory in migrationFiles holding the dialect's migrations.
+	migrations string
+}
+
+// rebind rewrites ? placeholders for the dialect. Question marks inside
+// quoted string literals are left alone.
+func (d dialect) rebind(query string) string {
+	if !d.numbered || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sqlDB and sqlTx wrap the database/sql types to rebind every query.
+type sqlDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.dialect.rebind(query), args...)
+}
+
+func (db *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+type sqlTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (tx *sqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *sqlTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *sqlTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *sqlTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.dialect.rebind(query))
+}
+
+func newSQLStore(db *sql.DB, d dialect) *sqlStore {
+	return &sqlStore{db: &sqlDB{DB: db, dialect: d}, dialect: d}
+}
+
+// Migrator returns the schema migrator for the database.
+func (s *sqlStore) Migrator() (*Migrator, error) {
+	return newMigrator(s.db, migrationFiles, s.dialect.migrations)
+}
+
+// migrate applies any pending migrations.
+func (s *sqlStore) migrate() error {
+	migrator, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	n, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Applied %d database migration(s)", n)
+	}
+	return nil
+}
+
+func (s *sqlStore) SaveGameData(ctx context.Context, steamID string, data *models.LocalGameData) error {
+	return s.inTx(ctx, func(tx *sqlTx) error {
+		return s.saveGameData(ctx, tx, steamID, data)
+	})
+}
+
+func (s *sqlStore) DeleteGameData(ctx context.Context, steamID string, appID int) error {
+	return s.inTx(ctx, func(tx *sqlTx) error {
+		return s.deleteGameData(ctx, tx, steamID, appID)
+	})
+}
+
+func (s *sqlStore) ApplyGameData(ctx context.Context, steamID string, save []*models.LocalGameData, del []int) error {
+	return s.inTx(ctx, func(tx *sqlTx) error {
+		for _, data := range save {
+			if err := s.saveGameData(ctx, tx, steamID, data); err != nil {
+				return err
+			}
+		}
+		for _, appID := range del {
+			if err := s.deleteGameData(ctx, tx, steamID, appID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// inTx runs fn in a transaction, committing if it returns nil.
+func (s *sqlStore) inTx(ctx context.Context, fn func(tx *sqlTx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) saveGameData(ctx context.Context, tx *sqlTx, steamID string, data *models.LocalGameData) error {
+	query := gameDataSelect + `WHERE g.steam_id = ? AND g.app_id = ?`
+	if s.dialect.lockRows {
+		query += ` FOR UPDATE OF g`
+	}
+	rows, err := tx.QueryContext(ctx, query, steamID, data.AppID)
+	if err != nil {
+		return err
+	}
+	existing, err := scanGameData(rows)
+	if err != nil {
+		return err
+	}
+
+	// created_at is only written on insert.
+	query = `
+	INSERT INTO user_game_data (steam_id, app_id, status, rating, is_favorite, play_order, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(steam_id, app_id) DO UPDATE SET
+		status = excluded.status,
+		rating = excluded.rating,
+		is_favorite = excluded.is_favorite,
+		play_order = excluded.play_order,
+		updated_at = excluded.updated_at;
+	`
+	_, err = tx.ExecContext(ctx, query, steamID, data.AppID, int(data.Status), data.Rating, data.IsFavorite,
+		data.PlayOrder, data.CreatedAt.Unix(), data.UpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	if data.Notes == nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM game_notes WHERE steam_id = ? AND app_id = ?`, steamID, data.AppID)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO game_notes (steam_id, app_id, notes) VALUES (?, ?, ?)
+		ON CONFLICT(steam_id, app_id) DO UPDATE SET notes = excluded.notes;
+		`, steamID, data.AppID, *data.Notes)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The revision records what was stored, to the second.
+	var previous *models.LocalGameData
+	saved := *data
+	saved.CreatedAt = time.Unix(data.CreatedAt.Unix(), 0)
+	saved.UpdatedAt = time.Unix(data.UpdatedAt.Unix(), 0)
+	if len(existing) > 0 {
+		previous = existing[0]
+		saved.CreatedAt = previous.CreatedAt
+		if previous.SameValues(data) {
+			return nil
+		}
+	}
+	return addRevision(ctx, tx, steamID, data.AppID, previous, &saved)
+}
+
+func (s *sqlStore) deleteGameData(ctx context.Context, tx *sqlTx, steamID string, appID int) error {
+	query := gameDataSelect + `WHERE g.steam_id = ? AND g.app_id = ?`
+	if s.dialect.lockRows {
+		query += ` FOR UPDATE OF g`
+	}
+	rows, err := tx.QueryContext(ctx, query, steamID, appID)
+	if err != nil {
+		return err
+	}
+	existing, err := scanGameData(rows)
+	if err != nil || len(existing) == 0 {
+		return err
+	}
+
+	for _, table := range []string{"user_game_data", "game_notes"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE steam_id = ? AND app_id = ?`, steamID, appID); err != nil {
+			return err
+		}
+	}
+	return addRevision(ctx, tx, steamID, appID, existing[0], nil)
+}
+
+// addRevision appends a change to game_data_history, with the ClientInfo from ctx.
+func addRevision(ctx context.Context, tx *sqlTx, steamID string, appID int, previous, data *models.LocalGameData) error {
+	previousJSON, err := gameDataJSON(previous)
+	if err != nil {
+		return err
+	}
+	dataJSON, err := gameDataJSON(data)
+	if err != nil {
+		return err
+	}
+	client := ClientInfoFrom(ctx)
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO game_data_history (steam_id, app_id, previous_data, new_data, changed_at, client_ip, user_agent, client)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, steamID, appID, previousJSON, dataJSON, time.Now().Unix(), client.IP, client.UserAgent, client.Client)
+	return err
+}
+
+// gameDataJSON encodes data for the history table, where nil is NULL.
+func gameDataJSON(data *models.LocalGameData) (sql.NullString, error) {
+	if data == nil {
+		return sql.NullString{}, nil
+	}
+	s, err := data.ToJSONString()
+	return sql.NullString{String: s, Valid: err == nil}, err
+}
+
+const revisionColumns = `id, app_id, previous_data, new_data, changed_at, client_ip, user_agent, client`
+
+func (s *sqlStore) ListGameDataHistory(ctx context.Context, steamID string, appID int) ([]models.GameDataRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM game_data_history WHERE steam_id = ? AND app_id = ? ORDER BY id DESC`
+	rows, err := s.db.QueryContext(ctx, query, steamID, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.GameDataRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *rev)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlStore) GetGameDataRevision(ctx context.Context, steamID string, appID int, revision int64) (*models.GameDataRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM game_data_history WHERE steam_id = ? AND app_id = ? AND id = ?`
+	rev, err := scanRevision(s.db.QueryRowContext(ctx, query, steamID, appID, revision))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rev, err
+}
+
+// scanRevision reads a row of revisionColumns.
+func scanRevision(row interface{ Scan(...interface{}) error }) (*models.GameDataRevision, error) {
+	var rev models.GameDataRevision
+	var previous, data, ip, userAgent, client sql.NullString
+	var changedAt int64
+	if err := row.Scan(&rev.Revision, &rev.AppID, &previous, &data, &changedAt, &ip, &userAgent, &client); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		raw sql.NullString
+		dst **models.LocalGameData
+	}{{previous, &rev.Previous}, {data, &rev.Data}} {
+		if !field.raw.Valid {
+			continue
+		}
+		decoded, err := models.LocalGameDataFromJSONString(field.raw.String)
+		if err != nil {
+			return nil, err
+		}
+		*field.dst = decoded
+	}
+	rev.ChangedAt = time.Unix(changedAt, 0)
+	rev.Client = models.ClientInfo{IP: ip.String, UserAgent: userAgent.String, Client: client.String}
+	return &rev, nil
+}
+
+const gameDataSelect = `
+	SELECT g.app_id, g.status, g.rating, g.is_favorite, g.play_order, g.created_at, g.updated_at, n.notes
+	FROM user_game_data g
+	LEFT JOIN game_notes n ON n.steam_id = g.steam_id AND n.app_id = g.app_id
+	`
+
+func (s *sqlStore) GetGameData(ctx context.Context, steamID string, appID int) (*models.LocalGameData, error) {
+	rows, err := s.db.QueryContext(ctx, gameDataSelect+`WHERE g.steam_id = ? AND g.app_id = ?`, steamID, appID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := scanGameData(rows)
+	if err != nil || len(results) == 0 {
+		return nil, err // Not found
+	}
+	return results[0], nil
+}
+
+func (s *sqlStore) GetAllGameData(ctx context.Context, steamID string) (map[int]*models.LocalGameData, error) {
+	rows, err := s.db.QueryContext(ctx, gameDataSelect+`WHERE g.steam_id = ?`, steamID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := scanGameData(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[int]*models.LocalGameData, len(list))
+	for _, data := range list {
+		results[data.AppID] = data
+	}
+	return results, nil
+}
+
+// gameDataSortColumns maps sort fields to columns.
+var gameDataSortColumns = map[GameDataSort]string{
+	SortByAppID:     "g.app_id",
+	SortByRating:    "g.rating",
+	SortByPlayOrder: "g.play_order",
+	SortByCreated:   "g.created_at",
+	SortByUpdated:   "g.updated_at",
+}
+
+func (s *sqlStore) QueryGameData(ctx context.Context, steamID string, q GameDataQuery) ([]*models.LocalGameData, error) {
+	query := gameDataSelect + `WHERE g.steam_id = ?`
+	args := []interface{}{steamID}
+	if q.Status != nil {
+		query += ` AND g.status = ?`
+		args = append(args, int(*q.Status))
+	}
+	if q.MinRating != nil {
+		query += ` AND g.rating >= ?`
+		args = append(args, *q.MinRating)
+	}
+	if q.MaxRating != nil {
+		query += ` AND g.rating <= ?`
+		args = append(args, *q.MaxRating)
+	}
+	if q.Favorite != nil {
+		query += ` AND g.is_favorite = ?`
+		args = append(args, *q.Favorite)
+	}
+
+	column, ok := gameDataSortColumns[q.Sort]
+	if !ok {
+		column = gameDataSortColumns[SortByAppID]
+	}
+	direction := "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+	query += ` ORDER BY ` + column + ` IS NULL, ` + column + ` ` + direction + `, g.app_id`
+	if q.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, q.Limit, q.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanGameData(rows)
+}
+
+// scanGameData reads rows selected with gameDataSelect and closes them.
+func scanGameData(rows *sql.Rows) ([]*models.LocalGameData, error) {
+	defer rows.Close()
+
+	results := []*models.LocalGameData{}
+	for rows.Next() {
+		var data models.LocalGameData
+		var status int
+		var rating sql.NullFloat64
+		var playOrder sql.NullInt64
+		var notes sql.NullString
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&data.AppID, &status, &rating, &data.IsFavorite, &playOrder, &createdAt, &updatedAt, &notes); err != nil {
+			return nil, err
+		}
+		data.Status = models.LocalGameStatus(status)
+		if rating.Valid {
+			data.Rating = &rating.Float64
+		}
+		if playOrder.Valid {
+			order := int(playOrder.Int64)
+			data.PlayOrder = &order
+		}
+		if notes.Valid {
+			data.Notes = &notes.String
+		}
+		data.CreatedAt = time.Unix(createdAt, 0)
+		data.UpdatedAt = time.Unix(updatedAt, 0)
+		results = append(results, &data)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlStore) SaveUser(ctx context.Context, user *models.SteamUser) error {
+	jsonData, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	if registeredUserOnly(ctx) {
+		res, err := s.db.ExecContext(ctx, `UPDATE users SET data = ? WHERE steam_id = ?`, string(jsonData), user.SteamID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err == nil {
+				err = ErrUnknownUser
+			}
+			return err
+		}
+		return nil
+	}
+
+	query := `
+	INSERT INTO users (steam_id, data)
+	VALUES (?, ?)
+	ON CONFLICT(steam_id) DO UPDATE SET data = excluded.data;
+	`
+	_, err = s.db.ExecContext(ctx, query, user.SteamID, string(jsonData))
+	return err
+}
+
+func (s *sqlStore) GetUser(ctx context.Context, steamID string) (*models.SteamUser, error) {
+	query := `SELECT data FROM users WHERE steam_id = ?`
+	row := s.db.QueryRowContext(ctx, query, steamID)
+
+	var dataStr string
+	if err := row.Scan(&dataStr); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var user models.SteamUser
+	if err := json.Unmarshal([]byte(dataStr), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *sqlStore) ListUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT steam_id FROM users ORDER BY steam_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// userTables are the tables holding rows keyed by a user's steam_id. users
+// and jobs come first: deleting their rows waits for, or fences off, writes
+// under WithRegisteredUser and progress writes by the user's running jobs
+// (see requireRegistered and SaveAchievementProgress), so none can land
+// after the erasure.
+var userTables = []string{
+	"users",
+	"jobs",
+	"user_game_data",
+	"game_notes",
+	"game_data_history",
+	"achievement_progress",
+	"library",
+	"library_events",
+	"playtime_totals",
+	"playtime_deltas",
+}
+
+func (s *sqlStore) EraseUser(ctx context.Context, steamID string, cache CacheFilter) (map[string]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := make(map[string]int64, len(userTables)+1)
+	for _, table := range userTables {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE steam_id = ?`, steamID)
+		if err != nil {
+			return nil, err
+		}
+		if deleted[table], err = res.RowsAffected(); err != nil {
+			return nil, err
+		}
+	}
+	keys, err := matchCacheKeys(ctx, tx, cache)
+	if err != nil {
+		return nil, err
+	}
+	for start := 0; start < len(keys); start += maxQueryParams {
+		chunk := keys[start:min(start+maxQueryParams, len(keys))]
+		args := make([]interface{}, len(chunk))
+		for i, key := range chunk {
+			args[i] = key
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM steam_cache WHERE cache_key IN (`+placeholders(len(chunk))+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		deleted["steam_cache"] += n
+	}
+
+	// Sessions issued up to now are refused from here on (see ErasedAt).
+	query := `
+	INSERT INTO erasures (steam_id, erased_at) VALUES (?, ?)
+	ON CONFLICT(steam_id) DO UPDATE SET erased_at = excluded.erased_at
+	`
+	if _, err := tx.ExecContext(ctx, query, steamID, time.Now().Unix()); err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// matchCacheKeys returns the keys of the cached responses filter matches.
+func matchCacheKeys(ctx context.Context, tx *sqlTx, filter CacheFilter) ([]string, error) {
+	var keys []string
+	for _, prefix := range filter.Prefixes {
+		// Compared as a substring rather than with LIKE, whose wildcards may
+		// appear in the prefix.
+		query := `SELECT cache_key, value FROM steam_cache WHERE SUBSTR(cache_key, 1, ?) = ?`
+		rows, err := tx.QueryContext(ctx, query, len(prefix), prefix)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key string
+			var value []byte
+			if err := rows.Scan(&key, &value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if filter.Match(key, value) {
+				keys = append(keys, key)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (s *sqlStore) ErasedAt(ctx context.Context, steamID string) (time.Time, error) {
+	var erasedAt int64
+	err := s.db.QueryRowContext(ctx, `SELECT erased_at FROM erasures WHERE steam_id = ?`, steamID).Scan(&erasedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(erasedAt, 0), nil
+}
+
+func (s *sqlStore) GetCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error) {
+	query := `SELECT value, expires_at FROM steam_cache WHERE cache_key = ?`
+	row := s.db.QueryRowContext(ctx, query, key)
+
+	var value []byte
+	var expiresAt int64
+	if err := row.Scan(&value, &expiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, err
+	}
+	return value, time.Unix(expiresAt, 0), nil
+}
+
+func (s *sqlStore) SetCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	query := `
+	INSERT INTO steam_cache (cache_key, value, expires_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT(cache_key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at;
+	`
+	_, err := s.db.ExecContext(ctx, query, key, value, expiresAt.Unix())
+	return err
+}
+
+func (s *sqlStore) DeleteCacheEntry(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM steam_cache WHERE cache_key = ?`, key)
+	return err
+}
+
+func (s *sqlStore) PurgeExpiredCacheEntries(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM steam_cache WHERE expires_at <= ?`, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *sqlStore) GetCatalogEntries(ctx context.Context, appIDs []int) (map[int]*models.GameMetadata, error) {
+	results := make(map[int]*models.GameMetadata)
+	var corrupt []int
+	for start := 0; start < len(appIDs); start += maxQueryParams {
+		chunk := appIDs[start:min(start+maxQueryParams, len(appIDs))]
+		query := `SELECT app_id, data FROM game_catalog WHERE data IS NOT NULL AND app_id IN (` + placeholders(len(chunk)) + `)`
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var appID int
+			var dataStr string
+			if err := rows.Scan(&appID, &dataStr); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			var meta models.GameMetadata
+			if err := json.Unmarshal([]byte(dataStr), &meta); err != nil {
+				log.Printf("store: catalog entry for app %d is not valid JSON, refetching it: %v", appID, err)
+				corrupt = append(corrupt, appID)
+				continue
+			}
+			results[appID] = &meta
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Undecodable metadata is only a stale copy of the storefront's, so it
+	// is made due at once for the refresher to replace.
+	for _, appID := range corrupt {
+		if err := s.ScheduleCatalogRefresh(ctx, appID, time.Unix(0, 0)); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (s *sqlStore) SaveCatalogEntry(ctx context.Context, meta *models.GameMetadata, nextRefresh time.Time) error {
+	jsonData, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO game_catalog (app_id, data, next_refresh)
+	VALUES (?, ?, ?)
+	ON CONFLICT(app_id) DO UPDATE SET data = excluded.data, next_refresh = excluded.next_refresh;
+	`
+	_, err = s.db.ExecContext(ctx, query, meta.AppID, string(jsonData), nextRefresh.Unix())
+	return err
+}
+
+func (s *sqlStore) HasCatalogEntry(ctx context.Context, appID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM game_catalog WHERE app_id = ?)`, appID).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqlStore) EnsureCatalogEntries(ctx context.Context, appIDs []int, due time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO game_catalog (app_id, next_refresh) VALUES (?, ?) ON CONFLICT(app_id) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, id := range appIDs {
+		if _, err := stmt.ExecContext(ctx, id, due.Unix()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) ScheduleCatalogRefresh(ctx context.Context, appID int, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE game_catalog SET next_refresh = ? WHERE app_id = ?`, at.Unix(), appID)
+	return err
+}
+
+func (s *sqlStore) ListCatalogDue(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	query := `SELECT app_id FROM game_catalog WHERE next_refresh <= ? ORDER BY next_refresh LIMIT ?`
+	rows, err := s.db.QueryContext(ctx, query, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *sqlStore) SaveAchievementProgress(ctx context.Context, steamID string, progress *models.AchievementProgress) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if lease, ok := jobLeaseFrom(ctx); ok {
+		// The shared lock holds off EraseUser's delete of the job until
+		// this write has committed, so the erasure deletes it too.
+		query := `SELECT 1 FROM jobs WHERE id = ? AND lease_id = ?`
+		if s.dialect.lockRows {
+			query += ` FOR SHARE`
+		}
+		var held int
+		if err := tx.QueryRowContext(ctx, query, lease.jobID, lease.leaseID).Scan(&held); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrLeaseLost
+			}
+			return err
+		}
+	}
+
+	query := `
+	INSERT INTO achievement_progress (steam_id, app_id, unlocked, total, last_unlock, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(steam_id, app_id) DO UPDATE SET
+		unlocked = excluded.unlocked,
+		total = excluded.total,
+		last_unlock = excluded.last_unlock,
+		updated_at = excluded.updated_at;
+	`
+	_, err = tx.ExecContext(ctx, query, steamID, progress.AppID, progress.Unlocked, progress.Total,
+		progress.LastUnlock, progress.UpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetAchievementProgress(ctx context.Context, steamID string) ([]models.AchievementProgress, error) {
+	query := `
+	SELECT app_id, unlocked, total, last_unlock, updated_at
+	FROM achievement_progress WHERE steam_id = ? ORDER BY app_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, steamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.AchievementProgress{}
+	for rows.Next() {
+		var p models.AchievementProgress
+		var updatedAt int64
+		if err := rows.Scan(&p.AppID, &p.Unlocked, &p.Total, &p.LastUnlock, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = time.Unix(updatedAt, 0)
+		results = append(results, p)
+	}
+	return results, rows.Err()
+}
+
+const librarySelect = `
+	SELECT app_id, name, playtime_forever, first_seen, last_seen, removed
+	FROM library WHERE steam_id = ?
+	`
+
+func (s *sqlStore) GetLibraryEntries(ctx context.Context, steamID string) (map[int]*models.LibraryEntry, error) {
+	rows, err := s.db.QueryContext(ctx, librarySelect, steamID)
+	if err != nil {
+		return nil, err
+	}
+	return scanLibraryEntries(rows)
+}
+
+func scanLibraryEntries(rows *sql.Rows) (map[int]*models.LibraryEntry, error) {
+	defer rows.Close()
+
+	results := make(map[int]*models.LibraryEntry)
+	for rows.Next() {
+		var e models.LibraryEntry
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&e.AppID, &e.Name, &e.PlaytimeForever, &firstSeen, &lastSeen, &e.Removed); err != nil {
+			return nil, err
+		}
+		e.FirstSeen = time.Unix(firstSeen, 0)
+		e.LastSeen = time.Unix(lastSeen, 0)
+		results[e.AppID] = &e
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlStore) UpdateLibrary(ctx context.Context, steamID string, diff LibraryDiff) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.requireRegistered(ctx, tx, steamID); err != nil {
+		return err
+	}
+	if lock := s.dialect.libraryLock; lock != "" {
+		if _, err := tx.ExecContext(ctx, lock, steamID); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.QueryContext(ctx, librarySelect, steamID)
+	if err != nil {
+		return err
+	}
+	previous, err := scanLibraryEntries(rows)
+	if err != nil {
+		return err
+	}
+
+	entries, events := diff(previous)
+	if len(entries) == 0 && len(events) == 0 {
+		return nil
+	}
+
+	upsert, err := tx.PrepareContext(ctx, `
+	INSERT INTO library (steam_id, app_id, name, playtime_forever, first_seen, last_seen, removed)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(steam_id, app_id) DO UPDATE SET
+		name = excluded.name,
+		playtime_forever = excluded.playtime_forever,
+		last_seen = excluded.last_seen,
+		removed = excluded.removed;
+	`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+
+	for _, e := range entries {
+		if _, err := upsert.ExecContext(ctx, steamID, e.AppID, e.Name, e.PlaytimeForever,
+			e.FirstSeen.Unix(), e.LastSeen.Unix(), e.Removed); err != nil {
+			return err
+		}
+	}
+
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO library_events (steam_id, app_id, name, type, at) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, ev := range events {
+		if _, err := insert.ExecContext(ctx, steamID, ev.AppID, ev.Name, string(ev.Type), ev.At.Unix()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) ListLibraryEvents(ctx context.Context, steamID string, since time.Time) ([]models.LibraryEvent, error) {
+	query := `
+	SELECT id, app_id, name, type, at
+	FROM library_events WHERE steam_id = ? AND at > ? ORDER BY at, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, steamID, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.LibraryEvent{}
+	for rows.Next() {
+		var ev models.LibraryEvent
+		var at int64
+		if err := rows.Scan(&ev.ID, &ev.AppID, &ev.Name, &ev.Type, &at); err != nil {
+			return nil, err
+		}
+		ev.At = time.Unix(at, 0)
+		results = append(results, ev)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlStore) IsAppOwned(ctx context.Context, appID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM library WHERE app_id = ? AND NOT removed)`, appID).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqlStore) GetPlaytimeTotals(ctx context.Context, steamID string) (map[int]int, error) {
+	query := `SELECT app_id, playtime_forever FROM playtime_totals WHERE steam_id = ?`
+	rows, err := s.db.QueryContext(ctx, query, steamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int]int)
+	for rows.Next() {
+		var appID, minutes int
+		if err := rows.Scan(&appID, &minutes); err != nil {
+			return nil, err
+		}
+		totals[appID] = minutes
+	}
+	return totals, rows.Err()
+}
+
+func (s *sqlStore) RecordPlaytime(ctx context.Context, steamID string, totals map[int]int, deltas []models.PlaytimeDelta) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.requireRegistered(ctx, tx, steamID); err != nil {
+		return err
+	}
+	upsert, err := tx.PrepareContext(ctx, `
+	INSERT INTO playtime_totals (steam_id, app_id, playtime_forever)
+	VALUES (?, ?, ?)
+	ON CONFLICT(steam_id, app_id) DO UPDATE SET playtime_forever = excluded.playtime_forever;
+	`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+
+	for appID, minutes := range totals {
+		if _, err := upsert.ExecContext(ctx, steamID, appID, minutes); err != nil {
+			return err
+		}
+	}
+
+	if err := addPlaytimeDeltas(ctx, tx, steamID, deltas); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetPlaytimeDeltas(ctx context.Context, steamID string, from, to time.Time) ([]models.PlaytimeDelta, error) {
+	query := `
+	SELECT app_id, day, minutes FROM playtime_deltas
+	WHERE steam_id = ? AND day >= ? AND day < ? ORDER BY day, app_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, steamID, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.PlaytimeDelta{}
+	for rows.Next() {
+		var d models.PlaytimeDelta
+		var day int64
+		if err := rows.Scan(&d.AppID, &day, &d.Minutes); err != nil {
+			return nil, err
+		}
+		d.Day = time.Unix(day, 0).UTC()
+		results = append(results, d)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlStore) ReplacePlaytimeDeltas(ctx context.Context, steamID string, before time.Time, deltas []models.PlaytimeDelta) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.requireRegistered(ctx, tx, steamID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM playtime_deltas WHERE steam_id = ? AND day < ?`, steamID, before.Unix()); err != nil {
+		return err
+	}
+	if err := addPlaytimeDeltas(ctx, tx, steamID, deltas); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addPlaytimeDeltas adds minutes to any delta already recorded for the same day.
+func addPlaytimeDeltas(ctx context.Context, tx *sqlTx, steamID string, deltas []models.PlaytimeDelta) error {
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO playtime_deltas (steam_id, app_id, day, minutes)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(steam_id, day, app_id) DO UPDATE SET minutes = playtime_deltas.minutes + excluded.minutes;
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, d := range deltas {
+		if _, err := stmt.ExecContext(ctx, steamID, d.AppID, d.Day.Unix(), d.Minutes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const jobColumns = `id, type, steam_id, status, payload, progress, result, error, attempts, max_attempts,
+	run_at, lease_id, leased_until, created_at, updated_at, finished_at`
+
+func (s *sqlStore) CreateJob(ctx context.Context, job *models.Job) error {
+	return s.inTx(ctx, func(tx *sqlTx) error {
+		if err := s.requireRegistered(ctx, tx, job.SteamID); err != nil {
+			return err
+		}
+		// idx_jobs_active allows one queued or running job per type and user;
+		// a conflict with it inserts nothing.
+		query := `INSERT INTO jobs (` + jobColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`
+		res, err := tx.ExecContext(ctx, query, job.ID, job.Type, job.SteamID, string(job.Status),
+			nullJSON(job.Payload), nullJSON(job.Progress), nullJSON(job.Result), job.Error, job.Attempts, job.MaxAttempts,
+			job.RunAt.Unix(), job.LeaseID, job.LeasedUntil.Unix(), job.CreatedAt.Unix(), job.UpdatedAt.Unix(), unixOrZero(job.FinishedAt))
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrActiveJobExists
+		}
+		return nil
+	})
+}
+
+// requireRegistered fails with ErrUnknownUser when ctx is under
+// WithRegisteredUser and steamID has no users row. The shared lock holds off
+// EraseUser's delete of the user until tx has committed, so the erasure
+// deletes what tx wrote too.
+func (s *sqlStore) requireRegistered(ctx context.Context, tx *sqlTx, steamID string) error {
+	if !registeredUserOnly(ctx) {
+		return nil
+	}
+	query := `SELECT 1 FROM users WHERE steam_id = ?`
+	if s.dialect.lockRows {
+		query += ` FOR SHARE`
+	}
+	var registered int
+	if err := tx.QueryRowContext(ctx, query, steamID).Scan(&registered); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUnknownUser
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+func (s *sqlStore) FindActiveJob(ctx context.Context, jobType, steamID string) (*models.Job, error) {
+	query := `
+	SELECT ` + jobColumns + ` FROM jobs
+	WHERE type = ? AND steam_id = ? AND status IN ('queued', 'running')
+	ORDER BY created_at LIMIT 1
+	`
+	job, err := scanJob(s.db.QueryRowContext(ctx, query, jobType, steamID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+func (s *sqlStore) LeaseJob(ctx context.Context, now time.Time, leaseID string, leasedUntil time.Time) (*models.Job, error) {
+	// A single UPDATE ... RETURNING is atomic, so two workers can't claim the
+	// same job; where the database supports it, rows already being claimed are skipped.
+	query := `
+	UPDATE jobs SET status = 'running', lease_id = ?, leased_until = ?, attempts = attempts + 1, updated_at = ?
+	WHERE id = (
+		SELECT id FROM jobs
+		WHERE (status = 'queued' AND run_at <= ?) OR (status = 'running' AND leased_until <= ?)
+		ORDER BY run_at LIMIT 1` + s.dialect.skipLocked + `
+	)
+	RETURNING ` + jobColumns
+	job, err := scanJob(s.db.QueryRowContext(ctx, query, leaseID, leasedUntil.Unix(), now.Unix(), now.Unix(), now.Unix()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+func (s *sqlStore) RenewJobLease(ctx context.Context, id, leaseID string, leasedUntil time.Time, progress json.RawMessage) error {
+	query := `
+	UPDATE jobs SET leased_until = ?, progress = COALESCE(?, progress), updated_at = ?
+	WHERE id = ? AND lease_id = ? AND status = 'running'
+	`
+	res, err := s.db.ExecContext(ctx, query, leasedUntil.Unix(), nullJSON(progress), time.Now().Unix(), id, leaseID)
+	if err != nil {
+		return err
+	}
+	return leaseHeld(res)
+}
+
+func (s *sqlStore) FinishJobAttempt(ctx context.Context, job *models.Job, leaseID string) error {
+	query := `
+	UPDATE jobs SET status = ?, progress = ?, result = ?, error = ?, attempts = ?, run_at = ?,
+		lease_id = '', leased_until = 0, updated_at = ?, finished_at = ?
+	WHERE id = ? AND lease_id = ? AND status = 'running'
+	`
+	res, err := s.db.ExecContext(ctx, query, string(job.Status), nullJSON(job.Progress), nullJSON(job.Result),
+		job.Error, job.Attempts, job.RunAt.Unix(), job.UpdatedAt.Unix(), unixOrZero(job.FinishedAt), job.ID, leaseID)
+	if err != nil {
+		return err
+	}
+	return leaseHeld(res)
+}
+
+func (s *sqlStore) PurgeJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM jobs WHERE status IN ('completed', 'dead') AND finished_at < ?`, finishedBefore.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func leaseHeld(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func scanJob(row *sql.Row) (*models.Job, error) {
+	var job models.Job
+	var payload, progress, result sql.NullString
+	var runAt, leasedUntil, createdAt, updatedAt, finishedAt int64
+	err := row.Scan(&job.ID, &job.Type, &job.SteamID, &job.Status, &payload, &progress, &result, &job.Error,
+		&job.Attempts, &job.MaxAttempts, &runAt, &job.LeaseID, &leasedUntil, &createdAt, &updatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = rawJSON(payload)
+	job.Progress = rawJSON(progress)
+	job.Result = rawJSON(result)
+	job.RunAt = time.Unix(runAt, 0)
+	job.LeasedUntil = time.Unix(leasedUntil, 0)
+	job.CreatedAt = time.Unix(createdAt, 0)
+	job.UpdatedAt = time.Unix(updatedAt, 0)
+	if finishedAt != 0 {
+		t := time.Unix(finishedAt, 0)
+		job.FinishedAt = &t
+	}
+	return &job, nil
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
+
+func unixOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// maxQueryParams keeps IN (...) lists well under SQLite's bound-parameter limit.
+const maxQueryParams = 500
+
+// placeholders returns "?, ?, ..." with n entries.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
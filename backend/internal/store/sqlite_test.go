@@ -0,0 +1,57 @@
+package store_test
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/internal/store/storetest"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "steam_data.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		return s
+	})
+}
+
+func TestSQLiteCatalogRefetchesInvalidJSON(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "steam_data.db")
+	s, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	now := time.Now()
+	for _, appID := range []int{440, 570} {
+		if err := s.SaveCatalogEntry(ctx, &models.GameMetadata{AppID: appID, Name: "Game"}, now.Add(time.Hour)); err != nil {
+			t.Fatalf("SaveCatalogEntry: %v", err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `UPDATE game_catalog SET data = '{"appId": 570, "name": "trunc' WHERE app_id = 570`); err != nil {
+		t.Fatalf("corrupting entry: %v", err)
+	}
+
+	entries, err := s.GetCatalogEntries(ctx, []int{440, 570})
+	if err != nil {
+		t.Fatalf("GetCatalogEntries: %v", err)
+	}
+	if entries[440] == nil || entries[570] != nil {
+		t.Errorf("GetCatalogEntries = %v; want 440 and not the undecodable 570", entries)
+	}
+	if due, err := s.ListCatalogDue(ctx, now, 10); err != nil || len(due) != 1 || due[0] != 570 {
+		t.Errorf("ListCatalogDue = %v, %v; want the undecodable 570", due, err)
+	}
+}
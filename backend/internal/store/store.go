@@ -1,12 +1,257 @@
 package store
 
-import "backend/internal/models"
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when a worker updates a job it no longer holds the lease on.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// ErrActiveJobExists is returned by CreateJob when the user already has a
+// queued or running job of the same type.
+var ErrActiveJobExists = errors.New("an active job of this type already exists")
+
+// ErrUnknownUser is returned by writes made under WithRegisteredUser for a
+// user who is not, or no longer, registered.
+var ErrUnknownUser = errors.New("user is not registered")
 
 type Store interface {
-	SaveGameData(steamID string, data *models.LocalGameData) error
-	GetGameData(steamID string, appID int) (*models.LocalGameData, error)
-	GetAllGameData(steamID string) (map[int]*models.LocalGameData, error)
-	SaveUser(user *models.SteamUser) error
-	GetUser(steamID string) (*models.SteamUser, error)
+	// SaveGameData upserts data. Stores that implement HistoryStore record
+	// the change as a revision, with the ClientInfo from ctx.
+	SaveGameData(ctx context.Context, steamID string, data *models.LocalGameData) error
+	// DeleteGameData removes the game's data, if any. Stores that implement
+	// HistoryStore record the deletion as a revision.
+	DeleteGameData(ctx context.Context, steamID string, appID int) error
+	// ApplyGameData saves every entry of save and then deletes every app in
+	// del, as SaveGameData and DeleteGameData would, but all or nothing.
+	ApplyGameData(ctx context.Context, steamID string, save []*models.LocalGameData, del []int) error
+	GetGameData(ctx context.Context, steamID string, appID int) (*models.LocalGameData, error)
+	GetAllGameData(ctx context.Context, steamID string) (map[int]*models.LocalGameData, error)
+	// QueryGameData returns the user's game data matching q, in q's order.
+	QueryGameData(ctx context.Context, steamID string, q GameDataQuery) ([]*models.LocalGameData, error)
+	// SaveUser upserts user. Under WithRegisteredUser it only updates, and
+	// fails with ErrUnknownUser if the user is gone.
+	SaveUser(ctx context.Context, user *models.SteamUser) error
+	GetUser(ctx context.Context, steamID string) (*models.SteamUser, error)
+	// ListUserIDs returns the SteamIDs of all registered users.
+	ListUserIDs(ctx context.Context) ([]string, error)
 	Close() error
 }
+
+// GameDataSort names the field QueryGameData orders by.
+type GameDataSort string
+
+const (
+	SortByAppID     GameDataSort = "appId"
+	SortByRating    GameDataSort = "rating"
+	SortByPlayOrder GameDataSort = "playOrder"
+	SortByCreated   GameDataSort = "created"
+	SortByUpdated   GameDataSort = "updated"
+)
+
+// GameDataQuery filters and orders QueryGameData. Nil filters match
+// everything. Games without a rating or play order sort last.
+type GameDataQuery struct {
+	Status    *models.LocalGameStatus
+	MinRating *float64
+	MaxRating *float64
+	Favorite  *bool
+	// Sort defaults to SortByAppID.
+	Sort GameDataSort
+	Desc bool
+	// Limit caps the number of results when positive; Offset only applies with a Limit.
+	Limit  int
+	Offset int
+}
+
+// CacheStore is the persistent tier for cached Steam API responses.
+// GetCacheEntry returns a nil value when the key is not present.
+type CacheStore interface {
+	GetCacheEntry(ctx context.Context, key string) ([]byte, time.Time, error)
+	SetCacheEntry(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+	DeleteCacheEntry(ctx context.Context, key string) error
+	PurgeExpiredCacheEntries(ctx context.Context, now time.Time) (int64, error)
+}
+
+// CatalogStore keeps storefront metadata per app along with when it is next due
+// for a refresh. Entries may exist before their metadata has been fetched.
+type CatalogStore interface {
+	// GetCatalogEntries returns fetched metadata for the given apps; apps with
+	// nothing fetched yet are absent from the map. So are apps whose metadata
+	// can't be decoded, which are made due for a refresh.
+	GetCatalogEntries(ctx context.Context, appIDs []int) (map[int]*models.GameMetadata, error)
+	SaveCatalogEntry(ctx context.Context, meta *models.GameMetadata, nextRefresh time.Time) error
+	// HasCatalogEntry reports whether appID is in the catalog, fetched or not.
+	HasCatalogEntry(ctx context.Context, appID int) (bool, error)
+	// EnsureCatalogEntries adds empty entries, due at the given time, for apps not yet in the catalog.
+	EnsureCatalogEntries(ctx context.Context, appIDs []int, due time.Time) error
+	ScheduleCatalogRefresh(ctx context.Context, appID int, at time.Time) error
+	// ListCatalogDue returns up to limit app IDs whose refresh time has passed, oldest first.
+	ListCatalogDue(ctx context.Context, now time.Time, limit int) ([]int, error)
+}
+
+// ProgressStore keeps per-game achievement completion for each user.
+type ProgressStore interface {
+	// SaveAchievementProgress upserts one game's progress. Under a job lease
+	// (see WithJobLease) it fails with ErrLeaseLost once the job no longer
+	// holds it, say because the user was erased.
+	SaveAchievementProgress(ctx context.Context, steamID string, progress *models.AchievementProgress) error
+	GetAchievementProgress(ctx context.Context, steamID string) ([]models.AchievementProgress, error)
+}
+
+// LibraryDiff works out what to save given every stored library entry of a
+// user, keyed by app ID.
+type LibraryDiff func(previous map[int]*models.LibraryEntry) ([]models.LibraryEntry, []models.LibraryEvent)
+
+// LibraryStore persists each user's owned games and the changes detected
+// between refreshes.
+type LibraryStore interface {
+	// GetLibraryEntries returns every game seen in the user's library, including removed ones.
+	GetLibraryEntries(ctx context.Context, steamID string) (map[int]*models.LibraryEntry, error)
+	// UpdateLibrary reads the user's library, passes it to diff and upserts
+	// the entries and appends the events diff returns, all in one
+	// transaction. Updates for the same user never interleave. Under
+	// WithRegisteredUser it fails with ErrUnknownUser if the user is gone.
+	UpdateLibrary(ctx context.Context, steamID string, diff LibraryDiff) error
+	// ListLibraryEvents returns the user's events after since, oldest first.
+	ListLibraryEvents(ctx context.Context, steamID string, since time.Time) ([]models.LibraryEvent, error)
+	// IsAppOwned reports whether appID is currently in any user's library.
+	IsAppOwned(ctx context.Context, appID int) (bool, error)
+}
+
+// PlaytimeStore keeps playtime history as per-day deltas, plus the last
+// cumulative playtime seen per game to compute the next delta from. Under
+// WithRegisteredUser its writes fail with ErrUnknownUser if the user is gone.
+type PlaytimeStore interface {
+	// GetPlaytimeTotals returns the last recorded playtime_forever per app.
+	GetPlaytimeTotals(ctx context.Context, steamID string) (map[int]int, error)
+	// RecordPlaytime saves new totals and adds deltas to their days in a single transaction.
+	RecordPlaytime(ctx context.Context, steamID string, totals map[int]int, deltas []models.PlaytimeDelta) error
+	// GetPlaytimeDeltas returns deltas with from <= day < to, oldest first.
+	GetPlaytimeDeltas(ctx context.Context, steamID string, from, to time.Time) ([]models.PlaytimeDelta, error)
+	// ReplacePlaytimeDeltas swaps all deltas before the given day for the given ones.
+	ReplacePlaytimeDeltas(ctx context.Context, steamID string, before time.Time, deltas []models.PlaytimeDelta) error
+}
+
+// JobStore is the persistent job queue. Running jobs are leased: only the
+// holder of the current lease may update them, and once a lease expires
+// (say, the server died) another worker may take the job over.
+type JobStore interface {
+	// CreateJob saves a new job. It fails with ErrActiveJobExists if the
+	// user already has a queued or running job of the same type, and under
+	// WithRegisteredUser with ErrUnknownUser if the job's user is gone.
+	CreateJob(ctx context.Context, job *models.Job) error
+	// GetJob returns nil if there is no job with that ID.
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	// FindActiveJob returns a queued or running job of jobType for steamID, or nil.
+	FindActiveJob(ctx context.Context, jobType, steamID string) (*models.Job, error)
+	// LeaseJob claims the next runnable job, queued and due or running with an
+	// expired lease, increments its attempts and returns it. It returns nil
+	// when nothing is runnable.
+	LeaseJob(ctx context.Context, now time.Time, leaseID string, leasedUntil time.Time) (*models.Job, error)
+	// RenewJobLease extends the lease and saves progress.
+	RenewJobLease(ctx context.Context, id, leaseID string, leasedUntil time.Time, progress json.RawMessage) error
+	// FinishJobAttempt saves the outcome of an attempt (status, progress,
+	// result, error, attempts, run time) and releases the lease.
+	FinishJobAttempt(ctx context.Context, job *models.Job, leaseID string) error
+	// PurgeJobs deletes completed and dead jobs that finished before the
+	// given time and returns how many it deleted.
+	PurgeJobs(ctx context.Context, finishedBefore time.Time) (int64, error)
+}
+
+// HistoryStore reads the append-only history of changes to local game data.
+// Revisions are written by SaveGameData; saves that change nothing but the
+// timestamps are not recorded.
+type HistoryStore interface {
+	// ListGameDataHistory returns the game's revisions, newest first.
+	ListGameDataHistory(ctx context.Context, steamID string, appID int) ([]models.GameDataRevision, error)
+	// GetGameDataRevision returns one revision of the game, or nil if there is none.
+	GetGameDataRevision(ctx context.Context, steamID string, appID int, revision int64) (*models.GameDataRevision, error)
+}
+
+// ErasureStore deletes everything held about a user.
+type ErasureStore interface {
+	// EraseUser deletes the user, every row derived from them and, where the
+	// store holds the response cache, the entries cache matches, in one
+	// transaction. It records the erasure time, and returns how many rows it
+	// deleted from each table.
+	EraseUser(ctx context.Context, steamID string, cache CacheFilter) (map[string]int64, error)
+	// ErasedAt returns when the user was last erased, or the zero time.
+	ErasedAt(ctx context.Context, steamID string) (time.Time, error)
+}
+
+// CacheFilter selects cached responses. Only entries whose key starts with
+// one of Prefixes are read and passed to Match.
+type CacheFilter struct {
+	Prefixes []string
+	Match    func(key string, value []byte) bool
+}
+
+type jobLeaseKey struct{}
+
+type jobLease struct {
+	jobID, leaseID string
+}
+
+// WithJobLease marks ctx as belonging to a job attempt holding leaseID, so
+// that stores can refuse writes the job no longer has a right to make.
+func WithJobLease(ctx context.Context, jobID, leaseID string) context.Context {
+	return context.WithValue(ctx, jobLeaseKey{}, jobLease{jobID: jobID, leaseID: leaseID})
+}
+
+func jobLeaseFrom(ctx context.Context) (jobLease, bool) {
+	lease, ok := ctx.Value(jobLeaseKey{}).(jobLease)
+	return lease, ok
+}
+
+type registeredUserKey struct{}
+
+// WithRegisteredUser marks ctx as refreshing users who are already
+// registered, as the scheduler does. Writes under it for a user who has since
+// been erased fail with ErrUnknownUser rather than bring the user back.
+func WithRegisteredUser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, registeredUserKey{}, true)
+}
+
+func registeredUserOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(registeredUserKey{}).(bool)
+	return only
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo attaches info to ctx, to be recorded with the changes made under it.
+func WithClientInfo(ctx context.Context, info models.ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the ClientInfo attached to ctx, if any.
+func ClientInfoFrom(ctx context.Context) models.ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(models.ClientInfo)
+	return info
+}
+
+// Database is a store backing every feature, as SQLiteStore and
+// PostgresStore are.
+type Database interface {
+	Store
+	CacheStore
+	CatalogStore
+	ProgressStore
+	LibraryStore
+	PlaytimeStore
+	JobStore
+	HistoryStore
+	ErasureStore
+	// Migrator returns the schema migrator for the database.
+	Migrator() (*Migrator, error)
+}
+
+var (
+	_ Database = (*SQLiteStore)(nil)
+	_ Database = (*PostgresStore)(nil)
+)
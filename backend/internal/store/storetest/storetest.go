@@ -0,0 +1,691 @@
+// Package storetest is a conformance suite for store.Store implementations.
+// Each backend's tests call Run with a function that opens a store.
+package storetest
+
+import (
+	"backend/internal/models"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Run runs the suite against stores returned by open, which is called once
+// per subtest. Every subtest uses its own steam IDs, so open may return a
+// store that already holds data, such as a shared database.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"SaveGameDataOverwrites", testSaveGameDataOverwrites},
+		{"MissingRowsReturnNil", testMissingRowsReturnNil},
+		{"GetAllGameDataIsolation", testGetAllGameDataIsolation},
+		{"QueryGameData", testQueryGameData},
+		{"UserRoundTrip", testUserRoundTrip},
+		{"SaveUserRegisteredOnly", testSaveUserRegisteredOnly},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"DeleteGameData", testDeleteGameData},
+		{"ApplyGameData", testApplyGameData},
+		{"History", testHistory},
+		{"EraseUser", testEraseUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := open(t)
+			t.Cleanup(func() { s.Close() })
+			tt.fn(t, s)
+		})
+	}
+}
+
+var idCounter atomic.Int64
+
+// newSteamID returns a steam ID no earlier run of the suite has used.
+func newSteamID() string {
+	return fmt.Sprintf("7656%013d", (time.Now().UnixNano()/1000+idCounter.Add(1))%1e13)
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func testSaveGameDataOverwrites(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+	created := time.Unix(1700000000, 0)
+
+	first := &models.LocalGameData{
+		AppID:      440,
+		Rating:     ptr(7.5),
+		Notes:      ptr("first notes"),
+		Status:     models.StatusPlaying,
+		IsFavorite: true,
+		PlayOrder:  ptr(3),
+		CreatedAt:  created,
+		UpdatedAt:  created,
+	}
+	if err := s.SaveGameData(ctx, steamID, first); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	got := mustGetGameData(t, s, steamID, 440)
+	assertGameData(t, got, first)
+
+	updated := created.Add(time.Hour)
+	second := &models.LocalGameData{
+		AppID:     440,
+		Rating:    ptr(9.0),
+		Status:    models.StatusCompleted,
+		CreatedAt: updated,
+		UpdatedAt: updated,
+	}
+	if err := s.SaveGameData(ctx, steamID, second); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	got = mustGetGameData(t, s, steamID, 440)
+	// Everything is replaced except CreatedAt, which keeps the first save's.
+	want := *second
+	want.CreatedAt = created
+	assertGameData(t, got, &want)
+
+	all, err := s.GetAllGameData(ctx, steamID)
+	if err != nil {
+		t.Fatalf("GetAllGameData: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("GetAllGameData returned %d entries after overwriting one, want 1", len(all))
+	}
+}
+
+func testMissingRowsReturnNil(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+
+	data, err := s.GetGameData(ctx, steamID, 440)
+	if err != nil || data != nil {
+		t.Errorf("GetGameData for unknown user = %v, %v; want nil, nil", data, err)
+	}
+	user, err := s.GetUser(ctx, steamID)
+	if err != nil || user != nil {
+		t.Errorf("GetUser for unknown user = %v, %v; want nil, nil", user, err)
+	}
+	all, err := s.GetAllGameData(ctx, steamID)
+	if err != nil || len(all) != 0 {
+		t.Errorf("GetAllGameData for unknown user = %v, %v; want empty", all, err)
+	}
+	list, err := s.QueryGameData(ctx, steamID, store.GameDataQuery{})
+	if err != nil || len(list) != 0 {
+		t.Errorf("QueryGameData for unknown user = %v, %v; want empty", list, err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: 440, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	data, err = s.GetGameData(ctx, steamID, 570)
+	if err != nil || data != nil {
+		t.Errorf("GetGameData for unknown game = %v, %v; want nil, nil", data, err)
+	}
+	// Optional fields that were never set come back nil.
+	data = mustGetGameData(t, s, steamID, 440)
+	if data.Rating != nil || data.Notes != nil || data.PlayOrder != nil {
+		t.Errorf("unset fields came back as rating %v, notes %v, play order %v; want nil", data.Rating, data.Notes, data.PlayOrder)
+	}
+}
+
+func testGetAllGameDataIsolation(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	alice, bob := newSteamID(), newSteamID()
+	now := time.Unix(1700000000, 0)
+
+	for _, appID := range []int{10, 20} {
+		if err := s.SaveGameData(ctx, alice, &models.LocalGameData{AppID: appID, Notes: ptr("alice"), CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	// Bob rates one of the same games; it must not touch Alice's row.
+	if err := s.SaveGameData(ctx, bob, &models.LocalGameData{AppID: 20, Notes: ptr("bob"), CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+
+	for steamID, want := range map[string][]int{alice: {10, 20}, bob: {20}} {
+		all, err := s.GetAllGameData(ctx, steamID)
+		if err != nil {
+			t.Fatalf("GetAllGameData: %v", err)
+		}
+		var got []int
+		for appID, data := range all {
+			got = append(got, appID)
+			if data.AppID != appID {
+				t.Errorf("GetAllGameData key %d holds app %d", appID, data.AppID)
+			}
+		}
+		sort.Ints(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("GetAllGameData(%s) = apps %v, want %v", steamID, got, want)
+		}
+	}
+	if data := mustGetGameData(t, s, alice, 20); data.Notes == nil || *data.Notes != "alice" {
+		t.Errorf("Alice's notes were changed by Bob's save: %v", data.Notes)
+	}
+}
+
+func testQueryGameData(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+	base := time.Unix(1700000000, 0)
+
+	games := []*models.LocalGameData{
+		{AppID: 1, Rating: ptr(8.0), Status: models.StatusPlaying, IsFavorite: true, PlayOrder: ptr(2)},
+		{AppID: 2, Rating: ptr(5.0), Status: models.StatusBacklog, PlayOrder: ptr(1)},
+		{AppID: 3, Status: models.StatusBacklog, IsFavorite: true},
+		{AppID: 4, Rating: ptr(8.0), Status: models.StatusCompleted},
+	}
+	for i, g := range games {
+		g.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		g.UpdatedAt = g.CreatedAt
+		if err := s.SaveGameData(ctx, steamID, g); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    store.GameDataQuery
+		want []int
+	}{
+		{"all by app ID", store.GameDataQuery{}, []int{1, 2, 3, 4}},
+		{"status", store.GameDataQuery{Status: ptr(models.StatusBacklog)}, []int{2, 3}},
+		{"favorite", store.GameDataQuery{Favorite: ptr(true)}, []int{1, 3}},
+		{"not favorite", store.GameDataQuery{Favorite: ptr(false)}, []int{2, 4}},
+		{"min rating skips unrated", store.GameDataQuery{MinRating: ptr(6.0)}, []int{1, 4}},
+		{"max rating skips unrated", store.GameDataQuery{MaxRating: ptr(6.0)}, []int{2}},
+		{"rating ascending, unrated last", store.GameDataQuery{Sort: store.SortByRating}, []int{2, 1, 4, 3}},
+		{"rating descending, unrated last", store.GameDataQuery{Sort: store.SortByRating, Desc: true}, []int{1, 4, 2, 3}},
+		{"play order, unset last", store.GameDataQuery{Sort: store.SortByPlayOrder}, []int{2, 1, 3, 4}},
+		{"created descending", store.GameDataQuery{Sort: store.SortByCreated, Desc: true}, []int{4, 3, 2, 1}},
+		{"limit and offset", store.GameDataQuery{Limit: 2, Offset: 1}, []int{2, 3}},
+		{"offset past the end", store.GameDataQuery{Limit: 2, Offset: 10}, nil},
+	}
+	for _, tt := range tests {
+		list, err := s.QueryGameData(ctx, steamID, tt.q)
+		if err != nil {
+			t.Fatalf("%s: QueryGameData: %v", tt.name, err)
+		}
+		var got []int
+		for _, data := range list {
+			got = append(got, data.AppID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got apps %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testUserRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	first, second := newSteamID(), newSteamID()
+
+	user := &models.SteamUser{
+		SteamID:                  first,
+		PersonName:               "Gordon",
+		ProfileURL:               "https://steamcommunity.com/id/gordon/",
+		Avatar:                   "https://avatars.example/a.jpg",
+		LastLogoff:               1700000000,
+		PersonState:              1,
+		CommunityVisibilityState: 3,
+	}
+	if err := s.SaveUser(ctx, user); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	got, err := s.GetUser(ctx, first)
+	if err != nil || got == nil {
+		t.Fatalf("GetUser = %v, %v", got, err)
+	}
+	if *got != *user {
+		t.Errorf("GetUser = %+v, want %+v", *got, *user)
+	}
+
+	renamed := *user
+	renamed.PersonName = "Freeman"
+	if err := s.SaveUser(ctx, &renamed); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if got, _ := s.GetUser(ctx, first); got == nil || got.PersonName != "Freeman" {
+		t.Errorf("GetUser after update = %+v, want name Freeman", got)
+	}
+
+	if err := s.SaveUser(ctx, &models.SteamUser{SteamID: second}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	ids, err := s.ListUserIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListUserIDs: %v", err)
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("ListUserIDs is not sorted: %v", ids)
+	}
+	count := 0
+	for _, id := range ids {
+		if id == first || id == second {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("ListUserIDs listed %d of the 2 saved users once each: %v", count, ids)
+	}
+}
+
+func testSaveUserRegisteredOnly(t *testing.T, s store.Store) {
+	ctx := store.WithRegisteredUser(context.Background())
+	known, unknown := newSteamID(), newSteamID()
+
+	if err := s.SaveUser(context.Background(), &models.SteamUser{SteamID: known, PersonName: "before"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SaveUser(ctx, &models.SteamUser{SteamID: known, PersonName: "after"}); err != nil {
+		t.Fatalf("SaveUser of a registered user: %v", err)
+	}
+	if user, err := s.GetUser(ctx, known); err != nil || user == nil || user.PersonName != "after" {
+		t.Errorf("GetUser = %+v, %v; want the update saved", user, err)
+	}
+
+	if err := s.SaveUser(ctx, &models.SteamUser{SteamID: unknown}); !errors.Is(err, store.ErrUnknownUser) {
+		t.Errorf("SaveUser of an unregistered user error = %v, want ErrUnknownUser", err)
+	}
+	if user, err := s.GetUser(ctx, unknown); err != nil || user != nil {
+		t.Errorf("GetUser = %+v, %v; want the user not created", user, err)
+	}
+}
+
+func testConcurrentWrites(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+	now := time.Unix(1700000000, 0)
+	const writers, games = 8, 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for appID := range games {
+				// Every writer saves every game, so the same rows are
+				// written concurrently.
+				data := &models.LocalGameData{AppID: appID, Rating: ptr(float64(w)), CreatedAt: now, UpdatedAt: now}
+				if err := s.SaveGameData(ctx, steamID, data); err != nil {
+					errs <- err
+					return
+				}
+			}
+			if err := s.SaveUser(ctx, &models.SteamUser{SteamID: steamID, PersonName: fmt.Sprint(w)}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write failed: %v", err)
+	}
+
+	all, err := s.GetAllGameData(ctx, steamID)
+	if err != nil {
+		t.Fatalf("GetAllGameData: %v", err)
+	}
+	if len(all) != games {
+		t.Errorf("GetAllGameData returned %d games, want %d", len(all), games)
+	}
+	for appID, data := range all {
+		if data.Rating == nil || *data.Rating < 0 || *data.Rating >= writers {
+			t.Errorf("game %d has rating %v, want one writer's value", appID, data.Rating)
+		}
+	}
+	if user, err := s.GetUser(ctx, steamID); err != nil || user == nil {
+		t.Errorf("GetUser after concurrent saves = %v, %v", user, err)
+	}
+}
+
+func testDeleteGameData(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+	now := time.Unix(1700000000, 0)
+
+	for _, appID := range []int{10, 20} {
+		if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: appID, Notes: ptr("notes"), CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	if err := s.DeleteGameData(ctx, steamID, 10); err != nil {
+		t.Fatalf("DeleteGameData: %v", err)
+	}
+	if data, err := s.GetGameData(ctx, steamID, 10); err != nil || data != nil {
+		t.Errorf("GetGameData after delete = %v, %v; want nil, nil", data, err)
+	}
+	all, err := s.GetAllGameData(ctx, steamID)
+	if err != nil || len(all) != 1 || all[20] == nil {
+		t.Errorf("GetAllGameData after delete = %v, %v; want only app 20", all, err)
+	}
+	if err := s.DeleteGameData(ctx, steamID, 10); err != nil {
+		t.Errorf("deleting again: %v", err)
+	}
+
+	// Saving again starts afresh: the notes went with the deleted row.
+	later := now.Add(time.Hour)
+	if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: 10, CreatedAt: later, UpdatedAt: later}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+	data := mustGetGameData(t, s, steamID, 10)
+	if data.Notes != nil || data.CreatedAt.Unix() != later.Unix() {
+		t.Errorf("re-created game has notes %v, created at %v; want nil, %v", data.Notes, data.CreatedAt, later)
+	}
+
+	history, ok := s.(store.HistoryStore)
+	if !ok {
+		return
+	}
+	revisions, err := history.ListGameDataHistory(ctx, steamID, 10)
+	if err != nil {
+		t.Fatalf("ListGameDataHistory: %v", err)
+	}
+	// Created, deleted (once: the second delete found nothing), created again.
+	if len(revisions) != 3 {
+		t.Fatalf("ListGameDataHistory returned %d revisions, want 3", len(revisions))
+	}
+	if deletion := revisions[1]; deletion.Data != nil || deletion.Previous == nil || !equalPtr(deletion.Previous.Notes, ptr("notes")) {
+		t.Errorf("deletion revision = %+v, want previous notes and no data", deletion)
+	}
+}
+
+func testApplyGameData(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	steamID := newSteamID()
+	now := time.Unix(1700000000, 0)
+
+	for _, appID := range []int{10, 20} {
+		if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: appID, Notes: ptr("notes"), CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	later := now.Add(time.Hour)
+	save := []*models.LocalGameData{
+		{AppID: 10, Rating: ptr(7.0), CreatedAt: later, UpdatedAt: later},
+		{AppID: 30, Status: models.StatusPlaying, CreatedAt: later, UpdatedAt: later},
+	}
+	if err := s.ApplyGameData(ctx, steamID, save, []int{20, 40}); err != nil {
+		t.Fatalf("ApplyGameData: %v", err)
+	}
+	all, err := s.GetAllGameData(ctx, steamID)
+	if err != nil || len(all) != 2 || all[10] == nil || all[30] == nil {
+		t.Fatalf("GetAllGameData after apply = %v, %v; want apps 10 and 30", all, err)
+	}
+	if !equalPtr(all[10].Rating, ptr(7.0)) || all[10].Notes != nil || all[10].CreatedAt.Unix() != now.Unix() {
+		t.Errorf("updated game = %+v, want rating 7, no notes, created at %v", all[10], now)
+	}
+
+	if history, ok := s.(store.HistoryStore); ok {
+		// Each save and delete is recorded as it would be on its own.
+		for appID, want := range map[int]int{10: 2, 20: 2, 30: 1, 40: 0} {
+			revisions, err := history.ListGameDataHistory(ctx, steamID, appID)
+			if err != nil {
+				t.Fatalf("ListGameDataHistory: %v", err)
+			}
+			if len(revisions) != want {
+				t.Errorf("app %d has %d revisions, want %d", appID, len(revisions), want)
+			}
+		}
+	}
+
+	// A save that cannot be recorded fails the whole batch. Stores that can
+	// hold a NaN rating have nothing to roll back.
+	bad := []*models.LocalGameData{
+		{AppID: 10, Rating: ptr(1.0), CreatedAt: later, UpdatedAt: later},
+		{AppID: 50, Rating: ptr(math.NaN()), CreatedAt: later, UpdatedAt: later},
+	}
+	if err := s.ApplyGameData(ctx, steamID, bad, []int{30}); err != nil {
+		if data := mustGetGameData(t, s, steamID, 10); !equalPtr(data.Rating, ptr(7.0)) {
+			t.Errorf("failed apply left rating %v, want 7", deref(data.Rating))
+		}
+		if data, _ := s.GetGameData(ctx, steamID, 30); data == nil {
+			t.Error("failed apply deleted app 30")
+		}
+		if data, _ := s.GetGameData(ctx, steamID, 50); data != nil {
+			t.Error("failed apply saved app 50")
+		}
+	}
+}
+
+func testHistory(t *testing.T, s store.Store) {
+	history, ok := s.(store.HistoryStore)
+	if !ok {
+		t.Skip("store does not implement HistoryStore")
+	}
+	steamID := newSteamID()
+	client := models.ClientInfo{IP: "203.0.113.7", UserAgent: "test-agent", Client: "tracker/1.2"}
+	ctx := store.WithClientInfo(context.Background(), client)
+	now := time.Unix(1700000000, 0)
+
+	saves := []*models.LocalGameData{
+		{AppID: 440, Notes: ptr("first"), CreatedAt: now, UpdatedAt: now},
+		// Only the timestamp changes: not a revision.
+		{AppID: 440, Notes: ptr("first"), CreatedAt: now.Add(time.Minute), UpdatedAt: now.Add(time.Minute)},
+		{AppID: 440, Notes: ptr("second"), Rating: ptr(6.0), CreatedAt: now.Add(time.Hour), UpdatedAt: now.Add(time.Hour)},
+	}
+	for _, data := range saves {
+		if err := s.SaveGameData(ctx, steamID, data); err != nil {
+			t.Fatalf("SaveGameData: %v", err)
+		}
+	}
+	if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: 570, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("SaveGameData: %v", err)
+	}
+
+	revisions, err := history.ListGameDataHistory(ctx, steamID, 440)
+	if err != nil {
+		t.Fatalf("ListGameDataHistory: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("ListGameDataHistory returned %d revisions, want 2", len(revisions))
+	}
+	latest, first := revisions[0], revisions[1]
+	if latest.Revision <= first.Revision {
+		t.Errorf("revisions not newest first: %d then %d", latest.Revision, first.Revision)
+	}
+	if first.Previous != nil || first.Data == nil || !equalPtr(first.Data.Notes, ptr("first")) {
+		t.Errorf("first revision = %+v, want creation with notes \"first\"", first)
+	}
+	if latest.Previous == nil || !equalPtr(latest.Previous.Notes, ptr("first")) {
+		t.Errorf("latest revision's previous value = %+v, want notes \"first\"", latest.Previous)
+	}
+	if latest.Data == nil || !equalPtr(latest.Data.Notes, ptr("second")) || !equalPtr(latest.Data.Rating, ptr(6.0)) {
+		t.Errorf("latest revision's new value = %+v, want notes \"second\" and rating 6", latest.Data)
+	}
+	// The recorded values are what was stored, so CreatedAt is the first save's.
+	if latest.Data != nil && latest.Data.CreatedAt.Unix() != now.Unix() {
+		t.Errorf("latest revision created at %v, want %v", latest.Data.CreatedAt, now)
+	}
+	for _, rev := range revisions {
+		if rev.AppID != 440 || rev.Client != client || rev.ChangedAt.IsZero() {
+			t.Errorf("revision %d has app %d, client %+v, changed at %v", rev.Revision, rev.AppID, rev.Client, rev.ChangedAt)
+		}
+	}
+
+	got, err := history.GetGameDataRevision(ctx, steamID, 440, first.Revision)
+	if err != nil || got == nil || got.Revision != first.Revision || !equalPtr(got.Data.Notes, ptr("first")) {
+		t.Errorf("GetGameDataRevision(%d) = %+v, %v", first.Revision, got, err)
+	}
+	// Revisions are per game and per user.
+	if got, err := history.GetGameDataRevision(ctx, steamID, 570, first.Revision); err != nil || got != nil {
+		t.Errorf("GetGameDataRevision for another game = %+v, %v; want nil, nil", got, err)
+	}
+	if got, err := history.GetGameDataRevision(ctx, newSteamID(), 440, first.Revision); err != nil || got != nil {
+		t.Errorf("GetGameDataRevision for another user = %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func testEraseUser(t *testing.T, s store.Store) {
+	erasure, ok := s.(store.ErasureStore)
+	if !ok {
+		t.Skip("store does not implement ErasureStore")
+	}
+	ctx := context.Background()
+	alice, bob := newSteamID(), newSteamID()
+	now := time.Unix(1700000000, 0)
+
+	for _, steamID := range []string{alice, bob} {
+		if err := s.SaveUser(ctx, &models.SteamUser{SteamID: steamID}); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+		for _, appID := range []int{10, 20} {
+			if err := s.SaveGameData(ctx, steamID, &models.LocalGameData{AppID: appID, Notes: ptr("notes"), CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatalf("SaveGameData: %v", err)
+			}
+		}
+	}
+
+	// Where the store holds the cache, the filter picks the entries to erase:
+	// only keys with its prefix are read, and only matches are deleted.
+	cache, hasCache := s.(store.CacheStore)
+	prefix := "/users_" + alice + "/?"
+	erased := map[string]string{prefix + "a": alice}
+	kept := map[string]string{
+		prefix + "b":              bob,
+		"/users_" + bob + "/?a":   alice,
+		"/usersX" + alice + "/?a": alice,
+	}
+	if hasCache {
+		for _, entries := range []map[string]string{erased, kept} {
+			for key, value := range entries {
+				if err := cache.SetCacheEntry(ctx, key, []byte(value), now.Add(100*365*24*time.Hour)); err != nil {
+					t.Fatalf("SetCacheEntry: %v", err)
+				}
+			}
+		}
+	}
+	filter := store.CacheFilter{
+		Prefixes: []string{prefix},
+		Match: func(key string, value []byte) bool {
+			if !strings.HasPrefix(key, prefix) {
+				t.Errorf("filter asked about %s, which lacks its prefix", key)
+			}
+			return string(value) == alice
+		},
+	}
+
+	deleted, err := erasure.EraseUser(ctx, alice, filter)
+	if err != nil {
+		t.Fatalf("EraseUser: %v", err)
+	}
+	if deleted["users"] != 1 || deleted["user_game_data"] != 2 {
+		t.Errorf("EraseUser deleted %v; want 1 users row and 2 user_game_data rows", deleted)
+	}
+	if hasCache {
+		if deleted["steam_cache"] != int64(len(erased)) {
+			t.Errorf("EraseUser deleted %d cache entries, want %d", deleted["steam_cache"], len(erased))
+		}
+		for _, entries := range []map[string]string{erased, kept} {
+			for key := range entries {
+				value, _, err := cache.GetCacheEntry(ctx, key)
+				if err != nil {
+					t.Fatalf("GetCacheEntry: %v", err)
+				}
+				if _, want := kept[key]; (value != nil) != want {
+					t.Errorf("cache entry %s kept = %v, want %v", key, value != nil, want)
+				}
+			}
+		}
+	}
+
+	if user, err := s.GetUser(ctx, alice); err != nil || user != nil {
+		t.Errorf("GetUser after erasure = %v, %v; want nil, nil", user, err)
+	}
+	if all, err := s.GetAllGameData(ctx, alice); err != nil || len(all) != 0 {
+		t.Errorf("GetAllGameData after erasure = %v, %v; want empty", all, err)
+	}
+	ids, err := s.ListUserIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListUserIDs: %v", err)
+	}
+	for _, id := range ids {
+		if id == alice {
+			t.Errorf("ListUserIDs still lists the erased user")
+		}
+	}
+	if history, ok := s.(store.HistoryStore); ok {
+		if revisions, err := history.ListGameDataHistory(ctx, alice, 10); err != nil || len(revisions) != 0 {
+			t.Errorf("history after erasure = %d revisions, %v; want none", len(revisions), err)
+		}
+	}
+
+	if erasedAt, err := erasure.ErasedAt(ctx, alice); err != nil || erasedAt.IsZero() {
+		t.Errorf("ErasedAt after erasure = %v, %v; want the erasure time", erasedAt, err)
+	}
+
+	// Other users are untouched.
+	if erasedAt, err := erasure.ErasedAt(ctx, bob); err != nil || !erasedAt.IsZero() {
+		t.Errorf("ErasedAt for another user = %v, %v; want the zero time", erasedAt, err)
+	}
+	if user, err := s.GetUser(ctx, bob); err != nil || user == nil {
+		t.Errorf("GetUser for another user after erasure = %v, %v", user, err)
+	}
+	if all, err := s.GetAllGameData(ctx, bob); err != nil || len(all) != 2 {
+		t.Errorf("GetAllGameData for another user after erasure = %d games, %v; want 2", len(all), err)
+	}
+}
+
+func mustGetGameData(t *testing.T, s store.Store, steamID string, appID int) *models.LocalGameData {
+	t.Helper()
+	data, err := s.GetGameData(context.Background(), steamID, appID)
+	if err != nil {
+		t.Fatalf("GetGameData: %v", err)
+	}
+	if data == nil {
+		t.Fatalf("GetGameData(%s, %d) = nil, want a row", steamID, appID)
+	}
+	return data
+}
+
+// assertGameData compares game data. Times are compared to the second,
+// which is what the SQL stores keep.
+func assertGameData(t *testing.T, got, want *models.LocalGameData) {
+	t.Helper()
+	if got.AppID != want.AppID || got.Status != want.Status || got.IsFavorite != want.IsFavorite {
+		t.Errorf("got app %d status %d favorite %v, want app %d status %d favorite %v",
+			got.AppID, got.Status, got.IsFavorite, want.AppID, want.Status, want.IsFavorite)
+	}
+	if !equalPtr(got.Rating, want.Rating) {
+		t.Errorf("rating = %v, want %v", deref(got.Rating), deref(want.Rating))
+	}
+	if !equalPtr(got.Notes, want.Notes) {
+		t.Errorf("notes = %v, want %v", deref(got.Notes), deref(want.Notes))
+	}
+	if !equalPtr(got.PlayOrder, want.PlayOrder) {
+		t.Errorf("play order = %v, want %v", deref(got.PlayOrder), deref(want.PlayOrder))
+	}
+	if got.CreatedAt.Unix() != want.CreatedAt.Unix() {
+		t.Errorf("created at %v, want %v", got.CreatedAt, want.CreatedAt)
+	}
+	if got.UpdatedAt.Unix() != want.UpdatedAt.Unix() {
+		t.Errorf("updated at %v, want %v", got.UpdatedAt, want.UpdatedAt)
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}